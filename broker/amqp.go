@@ -0,0 +1,26 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the AMQP publisher and subscriber.
+package broker
+
+import (
+	"outbox/debugger/config"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-amqp/pkg/amqp"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// newAMQPPublisher creates an AMQP publisher on a durable fanout exchange named after the topic.
+func newAMQPPublisher(cfg *config.Config, logger watermill.LoggerAdapter) (message.Publisher, error) {
+	return amqp.NewPublisher(amqpConfig(cfg), logger)
+}
+
+// newAMQPSubscriber creates an AMQP subscriber on a durable queue named after the topic and the subscriber.
+func newAMQPSubscriber(cfg *config.Config, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+	return amqp.NewSubscriber(amqpConfig(cfg), logger)
+}
+
+// amqpConfig returns the durable pub/sub settings shared by the AMQP publisher and subscriber.
+func amqpConfig(cfg *config.Config) amqp.Config {
+	return amqp.NewDurablePubSubConfig(cfg.Broker.AMQP.URI, amqp.GenerateQueueNameTopicNameWithSuffix(cfg.PubSub.SubscriberName))
+}
@@ -0,0 +1,107 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the factories building the Watermill publisher and subscriber of the configured broker.
+package broker
+
+import (
+	"fmt"
+	"outbox/debugger/config"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// OrderingKeyMetadata is the message metadata key carrying the ordering key of an event.
+// Brokers supporting ordering or partitioning use it to keep the events of one key together.
+const OrderingKeyMetadata = "ordering_key"
+
+// NewPublisher creates the publisher of the broker selected by the configuration.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the broker settings.
+//   - logger: The Watermill logger used by the publisher.
+//
+// Returns:
+//   - The Watermill publisher, to be passed to the outbox manager.
+//   - An error if the broker is unknown or the publisher cannot be created.
+func NewPublisher(cfg *config.Config, logger watermill.LoggerAdapter) (message.Publisher, error) {
+	var (
+		publisher message.Publisher
+		err       error
+	)
+
+	switch cfg.Broker.Type {
+	case config.BrokerGCP:
+		publisher, err = newGCPPublisher(cfg, logger)
+	case config.BrokerKafka:
+		publisher, err = newKafkaPublisher(cfg, logger)
+	case config.BrokerNATS:
+		publisher, err = newNATSPublisher(cfg, logger)
+	case config.BrokerAMQP:
+		publisher, err = newAMQPPublisher(cfg, logger)
+	case config.BrokerRedis:
+		publisher, err = newRedisPublisher(cfg, logger)
+	case config.BrokerGoChannel:
+		publisher = newGoChannelPublisher(logger)
+	case config.BrokerSQL:
+		publisher, err = newSQLPublisher(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unknown broker %q", cfg.Broker.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s publisher: %w", cfg.Broker.Type, err)
+	}
+
+	return publisher, nil
+}
+
+// NewSubscriber creates the subscriber of the broker selected by the configuration.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the broker and subscription settings.
+//   - logger: The Watermill logger used by the subscriber.
+//
+// Behavior:
+//   - Uses the configured subscriber name as subscription, consumer group or queue name,
+//     so several listeners with the same name share the messages of a topic.
+//   - With pubsub.messageOrdering, subscribes to Pub/Sub with ordering enabled; the other brokers keep their
+//     own guarantees (Kafka orders per partition, which the publisher picks by ordering key).
+//
+// Returns:
+//   - The Watermill subscriber, to be registered on the listener router.
+//   - An error if the broker is unknown or the subscriber cannot be created.
+func NewSubscriber(cfg *config.Config, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+	var (
+		subscriber message.Subscriber
+		err        error
+	)
+
+	switch cfg.Broker.Type {
+	case config.BrokerGCP:
+		subscriber, err = newGCPSubscriber(cfg, logger)
+	case config.BrokerKafka:
+		subscriber, err = newKafkaSubscriber(cfg, logger)
+	case config.BrokerNATS:
+		subscriber, err = newNATSSubscriber(cfg, logger)
+	case config.BrokerAMQP:
+		subscriber, err = newAMQPSubscriber(cfg, logger)
+	case config.BrokerRedis:
+		subscriber, err = newRedisSubscriber(cfg, logger)
+	case config.BrokerGoChannel:
+		subscriber = goChannel(logger)
+	case config.BrokerSQL:
+		subscriber, err = newSQLSubscriber(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unknown broker %q", cfg.Broker.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s subscriber: %w", cfg.Broker.Type, err)
+	}
+
+	return subscriber, nil
+}
+
+// orderingKey returns the ordering key of a message.
+// It matches the marshaler callbacks of the brokers supporting ordering or partitioning.
+func orderingKey(topic string, msg *message.Message) (string, error) {
+	return msg.Metadata.Get(OrderingKeyMetadata), nil
+}
@@ -0,0 +1,56 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the Google Cloud Pub/Sub publisher and subscriber.
+package broker
+
+import (
+	"outbox/debugger/config"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-googlecloud/pkg/googlecloud"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// newGCPPublisher creates a Google Cloud Pub/Sub publisher with message ordering enabled.
+// The topic must already exist.
+func newGCPPublisher(cfg *config.Config, logger watermill.LoggerAdapter) (message.Publisher, error) {
+	return googlecloud.NewPublisher(googlecloud.PublisherConfig{
+		ProjectID:                 cfg.PubSub.ProjectID,
+		DoNotCreateTopicIfMissing: true,
+		EnableMessageOrdering:     true,
+		Marshaler:                 googlecloud.NewOrderingMarshaler(orderingKey),
+	}, logger)
+}
+
+// newGCPSubscriber creates a Google Cloud Pub/Sub subscriber on the configured subscription.
+// The topic pubsub.topicName is read from the "<subscriberName>" subscription and every other topic from the
+// "<subscriberName>-<topic>" subscription. The subscriptions must already exist, except with message ordering: since
+// ordering can only be enabled when a subscription is created, the "-ordered" suffix is appended to their names and
+// they are created if missing.
+func newGCPSubscriber(cfg *config.Config, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+	return googlecloud.NewSubscriber(googlecloud.SubscriberConfig{
+		GenerateSubscriptionName:         func(topic string) string { return gcpSubscriptionName(cfg, topic) },
+		ProjectID:                        cfg.PubSub.ProjectID,
+		DoNotCreateSubscriptionIfMissing: !cfg.PubSub.MessageOrdering,
+		SubscriptionConfig: pubsub.SubscriptionConfig{
+			EnableMessageOrdering: cfg.PubSub.MessageOrdering, // Deliver the messages of an ordering key in order
+			AckDeadline:           40 * time.Second,           // Set acknowledgment deadline
+		},
+	}, logger)
+}
+
+// orderedSubscriptionSuffix is appended to the subscription name to get the subscription with message ordering.
+const orderedSubscriptionSuffix = "-ordered"
+
+// gcpSubscriptionName returns the name of the subscription the listener reads the topic from.
+func gcpSubscriptionName(cfg *config.Config, topic string) string {
+	subscription := cfg.PubSub.SubscriberName
+	if topic != cfg.PubSub.TopicName {
+		subscription += "-" + topic
+	}
+	if cfg.PubSub.MessageOrdering {
+		subscription += orderedSubscriptionSuffix
+	}
+	return subscription
+}
@@ -0,0 +1,50 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the in-process Go channel publisher and subscriber.
+package broker
+
+import (
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+)
+
+var (
+	goChannelOnce     sync.Once            // Guards the creation of the shared Go channel.
+	goChannelInstance *gochannel.GoChannel // Go channel shared by every publisher and subscriber of the process.
+)
+
+// goChannel returns the Go channel shared by the publishers and subscribers of the process.
+//
+// Behavior:
+//   - Messages only reach subscribers running in the same process.
+//   - Messages published before a subscriber starts are kept and delivered once it subscribes.
+func goChannel(logger watermill.LoggerAdapter) *gochannel.GoChannel {
+	goChannelOnce.Do(func() {
+		goChannelInstance = gochannel.NewGoChannel(gochannel.Config{Persistent: true}, logger)
+	})
+	return goChannelInstance
+}
+
+// goChannelPublisher is the publisher of the shared Go channel handed to a single user.
+// Closing it leaves the channel open for the other publishers and the subscribers of the process.
+type goChannelPublisher struct {
+	*gochannel.GoChannel // Shared Go channel publishing the messages.
+}
+
+// newGoChannelPublisher creates a publisher of the Go channel shared by the process.
+//
+// Parameters:
+//   - logger: The Watermill logger used by the Go channel.
+//
+// Returns:
+//   - The publisher, whose Close does not close the shared Go channel.
+func newGoChannelPublisher(logger watermill.LoggerAdapter) message.Publisher {
+	return goChannelPublisher{GoChannel: goChannel(logger)}
+}
+
+// Close does nothing: the shared Go channel lives as long as the process.
+func (goChannelPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,29 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the Kafka publisher and subscriber.
+package broker
+
+import (
+	"outbox/debugger/config"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// newKafkaPublisher creates a Kafka publisher.
+// The ordering key is used as partition key, so the events of one key stay ordered within a partition.
+func newKafkaPublisher(cfg *config.Config, logger watermill.LoggerAdapter) (message.Publisher, error) {
+	return kafka.NewPublisher(kafka.PublisherConfig{
+		Brokers:   cfg.Broker.Kafka.Brokers,
+		Marshaler: kafka.NewWithPartitioningMarshaler(orderingKey),
+	}, logger)
+}
+
+// newKafkaSubscriber creates a Kafka subscriber in the consumer group named after the subscriber.
+func newKafkaSubscriber(cfg *config.Config, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+	return kafka.NewSubscriber(kafka.SubscriberConfig{
+		Brokers:       cfg.Broker.Kafka.Brokers,
+		Unmarshaler:   kafka.NewWithPartitioningMarshaler(orderingKey),
+		ConsumerGroup: cfg.PubSub.SubscriberName,
+	}, logger)
+}
@@ -0,0 +1,40 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the NATS publisher and subscriber.
+package broker
+
+import (
+	"outbox/debugger/config"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-nats/v2/pkg/nats"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// newNATSPublisher creates a NATS publisher, using JetStream when enabled.
+// With JetStream the stream of a topic is created if missing.
+func newNATSPublisher(cfg *config.Config, logger watermill.LoggerAdapter) (message.Publisher, error) {
+	return nats.NewPublisher(nats.PublisherConfig{
+		URL:       cfg.Broker.NATS.URL,
+		Marshaler: &nats.NATSMarshaler{},
+		JetStream: natsJetStreamConfig(cfg),
+	}, logger)
+}
+
+// newNATSSubscriber creates a NATS subscriber in the queue group named after the subscriber.
+func newNATSSubscriber(cfg *config.Config, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+	return nats.NewSubscriber(nats.SubscriberConfig{
+		URL:              cfg.Broker.NATS.URL,
+		QueueGroupPrefix: cfg.PubSub.SubscriberName,
+		Unmarshaler:      &nats.NATSMarshaler{},
+		JetStream:        natsJetStreamConfig(cfg),
+	}, logger)
+}
+
+// natsJetStreamConfig returns the JetStream settings shared by the NATS publisher and subscriber.
+func natsJetStreamConfig(cfg *config.Config) nats.JetStreamConfig {
+	return nats.JetStreamConfig{
+		Disabled:      !cfg.Broker.NATS.JetStream,
+		AutoProvision: true,
+		DurablePrefix: cfg.PubSub.SubscriberName,
+	}
+}
@@ -0,0 +1,38 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the Redis Streams publisher and subscriber.
+package broker
+
+import (
+	"outbox/debugger/config"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill-redisstream/pkg/redisstream"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/redis/go-redis/v9"
+)
+
+// newRedisPublisher creates a Redis Streams publisher writing to a stream named after the topic.
+func newRedisPublisher(cfg *config.Config, logger watermill.LoggerAdapter) (message.Publisher, error) {
+	return redisstream.NewPublisher(redisstream.PublisherConfig{
+		Client:     redisClient(cfg),
+		Marshaller: redisstream.DefaultMarshallerUnmarshaller{},
+	}, logger)
+}
+
+// newRedisSubscriber creates a Redis Streams subscriber in the consumer group named after the subscriber.
+func newRedisSubscriber(cfg *config.Config, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+	return redisstream.NewSubscriber(redisstream.SubscriberConfig{
+		Client:        redisClient(cfg),
+		Unmarshaller:  redisstream.DefaultMarshallerUnmarshaller{},
+		ConsumerGroup: cfg.PubSub.SubscriberName,
+	}, logger)
+}
+
+// redisClient creates the Redis client of the configured server.
+func redisClient(cfg *config.Config) redis.UniversalClient {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Broker.Redis.Addr,
+		Password: cfg.Broker.Redis.Password,
+		DB:       cfg.Broker.Redis.DB,
+	})
+}
@@ -0,0 +1,87 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the PostgreSQL publisher and subscriber backed by the outbox database.
+package broker
+
+import (
+	"database/sql"
+	"errors"
+	"outbox/debugger/config"
+	"outbox/debugger/enum"
+
+	"github.com/ThreeDotsLabs/watermill"
+	watermillsql "github.com/ThreeDotsLabs/watermill-sql/v3/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/message"
+	_ "github.com/lib/pq"
+)
+
+// sqlPublisher is a PostgreSQL publisher owning its database connection.
+type sqlPublisher struct {
+	message.Publisher         // Watermill SQL publisher.
+	db                *sql.DB // Connection closed together with the publisher.
+}
+
+// Close closes the publisher and its database connection.
+func (p *sqlPublisher) Close() error {
+	return errors.Join(p.Publisher.Close(), p.db.Close())
+}
+
+// sqlSubscriber is a PostgreSQL subscriber owning its database connection.
+type sqlSubscriber struct {
+	message.Subscriber         // Watermill SQL subscriber.
+	db                 *sql.DB // Connection closed together with the subscriber.
+}
+
+// Close closes the subscriber and its database connection.
+func (s *sqlSubscriber) Close() error {
+	return errors.Join(s.Subscriber.Close(), s.db.Close())
+}
+
+// newSQLPublisher creates a PostgreSQL publisher writing to a watermill_<topic> table of the outbox database.
+// The table is created if missing.
+func newSQLPublisher(cfg *config.Config, logger watermill.LoggerAdapter) (message.Publisher, error) {
+	db, err := sql.Open(enum.DbSqlDriverName, cfg.Database.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, err := watermillsql.NewPublisher(db, watermillsql.PublisherConfig{
+		SchemaAdapter:        sqlSchema(),
+		AutoInitializeSchema: true,
+	}, logger)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqlPublisher{Publisher: publisher, db: db}, nil
+}
+
+// newSQLSubscriber creates a PostgreSQL subscriber in the consumer group named after the subscriber.
+// The message and offset tables are created if missing.
+func newSQLSubscriber(cfg *config.Config, logger watermill.LoggerAdapter) (message.Subscriber, error) {
+	db, err := sql.Open(enum.DbSqlDriverName, cfg.Database.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriber, err := watermillsql.NewSubscriber(db, watermillsql.SubscriberConfig{
+		ConsumerGroup:    cfg.PubSub.SubscriberName,
+		SchemaAdapter:    sqlSchema(),
+		OffsetsAdapter:   watermillsql.DefaultPostgreSQLOffsetsAdapter{},
+		InitializeSchema: true,
+	}, logger)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqlSubscriber{Subscriber: subscriber, db: db}, nil
+}
+
+// sqlSchema returns the message table schema shared by the PostgreSQL publisher and subscriber.
+// Payloads are stored as BYTEA because outbox events are not required to be JSON.
+func sqlSchema() watermillsql.DefaultPostgreSQLSchema {
+	return watermillsql.DefaultPostgreSQLSchema{
+		GeneratePayloadType: func(topic string) string { return "BYTEA" },
+	}
+}
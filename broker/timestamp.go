@@ -0,0 +1,38 @@
+// Package broker provides the message brokers supported by the Outbox Debugger application.
+// This file defines the publisher decorator stamping the publish time on every message.
+package broker
+
+import (
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// PublishedAtMetadata is the message metadata key carrying the time the message was handed to the broker,
+// formatted as RFC3339 with nanoseconds.
+const PublishedAtMetadata = "published_at"
+
+// timestampPublisher stamps the publish time on the messages of the wrapped publisher.
+type timestampPublisher struct {
+	message.Publisher // Wrapped publisher.
+}
+
+// WithPublishTimestamp wraps a publisher so every message carries its publish time in PublishedAtMetadata.
+//
+// Parameters:
+//   - publisher: The publisher to wrap.
+//
+// Returns:
+//   - The wrapping publisher.
+func WithPublishTimestamp(publisher message.Publisher) message.Publisher {
+	return &timestampPublisher{Publisher: publisher}
+}
+
+// Publish stamps the current time on the messages and publishes them.
+func (p *timestampPublisher) Publish(topic string, messages ...*message.Message) error {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, msg := range messages {
+		msg.Metadata.Set(PublishedAtMetadata, now)
+	}
+	return p.Publisher.Publish(topic, messages...)
+}
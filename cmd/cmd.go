@@ -5,6 +5,7 @@ package cmd
 import (
 	"log"
 	"os"
+	"outbox/debugger/config"
 
 	"github.com/spf13/cobra" // Cobra library for building CLI applications.
 )
@@ -13,16 +14,21 @@ var (
 	// rootCmd is the base command for the Outbox Debugger application.
 	// It provides the primary entry point for all subcommands.
 	rootCmd = &cobra.Command{
-		Use:   "Outbox debugger Services", // Command usage description.
-		Short: "Outbox debugger",          // A brief description of the root command.
-		Long:  "Outbox debugger Services", // A longer description of the root command.
+		Use:               "Outbox debugger Services", // Command usage description.
+		Short:             "Outbox debugger",          // A brief description of the root command.
+		Long:              "Outbox debugger Services", // A longer description of the root command.
+		PersistentPreRunE: loadConfig,                 // Resolve the configuration before any subcommand runs.
 	}
+
+	// appConfig is the configuration resolved by loadConfig and shared by every subcommand.
+	appConfig *config.Config
 )
 
 // Execute initializes and runs the root command along with its subcommands.
 //
 // Behavior:
-//   - Registers subcommands (ListenerCmd, PublisherCmd, CronCmd, DbMigrateCmd).
+//   - Registers the persistent configuration flags on the root command.
+//   - Registers subcommands (ListenerCmd, PublisherCmd, CronCmd, DbMigrateCmd, InspectCmd, StatsCmd, RequeueCmd, PurgeCmd, OfflineCmd, ScenarioCmd, DeadLetterCmd).
 //   - Executes the root command based on user input.
 //   - Handles any errors during execution and logs them appropriately.
 //
@@ -30,15 +36,44 @@ var (
 //
 //	Execute this function in the main package to start the CLI application.
 func Execute() {
-	// Step 1: Register subcommands to the root command.
-	rootCmd.AddCommand(ListenerCmd())  // Register the Listener command.
-	rootCmd.AddCommand(PublisherCmd()) // Register the Publisher command.
-	rootCmd.AddCommand(CronCmd())      // Register the Cron command.
-	rootCmd.AddCommand(DbMigrateCmd()) // Register the Database Migration command.
+	// Step 1: Register the configuration flags shared by every subcommand.
+	config.RegisterFlags(rootCmd.PersistentFlags())
+
+	// Step 2: Register subcommands to the root command.
+	rootCmd.AddCommand(ListenerCmd())   // Register the Listener command.
+	rootCmd.AddCommand(PublisherCmd())  // Register the Publisher command.
+	rootCmd.AddCommand(CronCmd())       // Register the Cron command.
+	rootCmd.AddCommand(DbMigrateCmd())  // Register the Database Migration command.
+	rootCmd.AddCommand(InspectCmd())    // Register the Inspect command.
+	rootCmd.AddCommand(StatsCmd())      // Register the Stats command.
+	rootCmd.AddCommand(RequeueCmd())    // Register the Requeue command.
+	rootCmd.AddCommand(PurgeCmd())      // Register the Purge command.
+	rootCmd.AddCommand(OfflineCmd())    // Register the Offline command.
+	rootCmd.AddCommand(ScenarioCmd())   // Register the Scenario command.
+	rootCmd.AddCommand(DeadLetterCmd()) // Register the Dead Letter command.
 
-	// Step 2: Execute the root command.
+	// Step 3: Execute the root command.
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalln("Error: \n", err.Error()) // Log the error and terminate the program.
 		os.Exit(-1)                           // Exit with a non-zero status code.
 	}
 }
+
+// loadConfig resolves the layered configuration into appConfig.
+//
+// Parameters:
+//   - cmd: The command being executed.
+//   - args: Command-line arguments passed to the command.
+//
+// Returns:
+//   - nil if the configuration was loaded and validated.
+//   - An error object if the configuration file, environment or flags are invalid.
+func loadConfig(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cmd.Root().PersistentFlags())
+	if err != nil {
+		return err
+	}
+
+	appConfig = cfg
+	return nil
+}
@@ -3,11 +3,19 @@
 package cmd
 
 import (
+	"os"
+	"os/signal"
 	"outbox/debugger/services" // Package containing the cron service logic.
+	"syscall"
 
 	"github.com/spf13/cobra" // Cobra library for CLI command creation.
 )
 
+var (
+	// Flags for the "cron" command
+	dryRelay bool // Only log the rows the relay would publish, without running it.
+)
+
 var (
 	// cronCmd defines the "cron" command for starting the cron services.
 	cronCmd = &cobra.Command{
@@ -22,8 +30,10 @@ var (
 //
 // Behavior:
 //   - Defines the "cron" command and associates it with the execution logic.
+//   - Defines the dryRelay flag logging the rows the relay would publish instead of running it.
 //   - This command starts the cron services when invoked.
 func CronCmd() *cobra.Command {
+	cronCmd.Flags().BoolVar(&dryRelay, "dryRelay", false, "Only log the due rows the relay would publish, without running the relay; the outbox rows are left untouched")
 	return cronCmd
 }
 
@@ -34,16 +44,19 @@ func CronCmd() *cobra.Command {
 //   - args: Command-line arguments passed to the command.
 //
 // Behavior:
-//   - Invokes the StartCron function from the services package to initialize the cron services.
-//   - Returns an error if initialization fails.
+//   - Invokes the StartCron function from the services package to run the cron services, relaying to the
+//     publisher of the configured broker or, with --dryRelay, only logging the rows due for relay.
+//   - Shuts the relay down gracefully on SIGINT/SIGTERM: the cron batch in progress is drained, then the publisher
+//     and the database are closed and a summary of what was relayed is logged.
 //
 // Returns:
-//   - nil if the services start successfully.
-//   - An error object if something goes wrong during initialization.
+//   - nil if the services ran and shut down cleanly.
+//   - An error object if the publisher cannot be created, or if the publisher or the database could not be closed.
 func runCronServices(cmd *cobra.Command, args []string) error {
-	// Step 1: Register and start the cron services.
-	services.StartCron()
+	// Step 1: Stop the cron services on SIGINT/SIGTERM.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Step 2: Return nil to indicate successful execution.
-	return nil
+	// Step 2: Run the cron services until stopped.
+	return services.StartCron(ctx, appConfig, dryRelay)
 }
@@ -5,12 +5,12 @@ package cmd
 import (
 	"flag"
 	"fmt"
-	"os"
-	"outbox/debugger/enum"
+	"outbox/debugger/config"
 	"strconv"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -20,10 +20,12 @@ import (
 var (
 	// migrationCommand defines the "db" command for database migration.
 	migrationCommand = &cobra.Command{
-		Use:   "db",                 // Command usage text.
-		Short: "Database Migration", // Short description of the command.
+		Use:                "db",                 // Command usage text.
+		Short:              "Database Migration", // Short description of the command.
+		DisableFlagParsing: true,                 // Arguments such as `-dir migration up` are parsed by flags.
+		PersistentPreRunE:  parseMigrationFlags,  // Parse -dir and the configuration flags, then load the configuration.
 		Run: func(c *cobra.Command, args []string) {
-			DatabaseMigration() // Executes the database migration logic.
+			DatabaseMigration(appConfig, flags.Args()) // Executes the database migration logic.
 		},
 	}
 
@@ -55,21 +57,74 @@ Commands:
 For more features, visit https://github.com/golang-migrate/migrate/tree/master/cmd/migrate`
 )
 
+// parseMigrationFlags parses the arguments of the "db" command, which Cobra leaves untouched.
+//
+// Parameters:
+//   - c: The "db" command.
+//   - args: The raw command-line arguments following "db".
+//
+// Behavior:
+//   - Exposes the configuration flags of the root command to flags, so both `-dir migration up`
+//     and `--dbDsn=... up` keep working.
+//   - Parses the flags preceding the migration command, then loads the configuration like every other command.
+//
+// Returns:
+//   - nil if the configuration was loaded and validated.
+//   - An error object if the configuration is invalid.
+func parseMigrationFlags(c *cobra.Command, args []string) error {
+	// Step 1: Expose the configuration flags.
+	configFlags := c.Root().PersistentFlags()
+	configFlags.VisitAll(func(f *pflag.Flag) {
+		if flags.Lookup(f.Name) == nil {
+			flags.Var(&configFlag{fs: configFlags, flag: f}, f.Name, f.Usage)
+		}
+	})
+
+	// Step 2: Parse the arguments, then load the configuration.
+	flags.Usage = usage
+	flags.Parse(args)
+	return loadConfig(c, flags.Args())
+}
+
+// configFlag exposes a configuration flag of the root command to the flag package,
+// marking it as changed on the root command when set.
+type configFlag struct {
+	fs   *pflag.FlagSet // Persistent flags of the root command.
+	flag *pflag.Flag    // Configuration flag.
+}
+
+// String returns the current value of the flag.
+func (f *configFlag) String() string {
+	if f.flag == nil {
+		return ""
+	}
+	return f.flag.Value.String()
+}
+
+// Set sets the flag on the root command.
+func (f *configFlag) Set(value string) error {
+	return f.fs.Set(f.flag.Name, value)
+}
+
+// IsBoolFlag lets boolean flags be given without a value.
+func (f *configFlag) IsBoolFlag() bool {
+	return f.flag != nil && f.flag.Value.Type() == "bool"
+}
+
 // DatabaseMigration executes the database migration logic.
 //
+// Parameters:
+//   - cfg: The runtime configuration providing the database connection string.
+//   - args: The migration command and its argument, left after parseMigrationFlags parsed the flags.
+//
 // Behavior:
-//   - Parses command-line arguments and executes the specified migration operation.
+//   - Executes the specified migration operation.
 //   - Initializes the migration instance with the provided migration files and database connection string.
 //   - Supports `up`, `down`, and `goto` operations with optional steps or version arguments.
 //
 // Error Handling:
 //   - Logs fatal errors and terminates execution if a migration operation fails.
-func DatabaseMigration() {
-	flags.Usage = usage
-	flags.Parse(os.Args[2:])
-
-	args := flags.Args()
-
+func DatabaseMigration(cfg *config.Config, args []string) {
 	if len(args) == 0 {
 		flags.Usage()
 		return
@@ -81,7 +136,7 @@ func DatabaseMigration() {
 	)
 
 	// Initialize the database connection string.
-	connstr = enum.DbDSN
+	connstr = cfg.Database.DSN
 
 	// Create a new migration instance with the specified directory and connection string.
 	m, err := migrate.New(
@@ -0,0 +1,223 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the "deadletter" command for listing and replaying the poison messages stored by the listener.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"outbox/debugger/services"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for the "deadletter list" command
+	deadLetterListFilter deadLetterFilterFlags // Dead letter filter flags.
+	deadLetterListOutput string                // Output format: table or json.
+
+	// Flags for the "deadletter replay" command
+	deadLetterReplayFilter  deadLetterFilterFlags // Dead letter filter flags.
+	deadLetterReplayToTopic string                // Topic to publish to instead of the original topic.
+	deadLetterReplayDryRun  bool                  // Only show what would be replayed.
+)
+
+var (
+	// deadLetterCmd defines the "deadletter" command grouping the dead letter subcommands.
+	deadLetterCmd = &cobra.Command{
+		Use:   "deadletter",                                                                    // Command usage text.
+		Short: "Dead-lettered listener messages",                                               // Brief description of the command.
+		Long:  "List and replay the poison messages stored in the listener_dead_letter table.", // Detailed description of the command.
+	}
+
+	// deadLetterListCmd defines the "deadletter list" command for listing the dead letters.
+	deadLetterListCmd = &cobra.Command{
+		Use:   "list",                                                                             // Command usage text.
+		Short: "List dead letters",                                                                // Brief description of the command.
+		Long:  "List the poison messages stored in the listener_dead_letter table, oldest first.", // Detailed description of the command.
+		RunE:  runDeadLetterList,                                                                  // Function to execute when the command is run.
+	}
+
+	// deadLetterReplayCmd defines the "deadletter replay" command for publishing the dead letters again.
+	deadLetterReplayCmd = &cobra.Command{
+		Use:   "replay",              // Command usage text.
+		Short: "Replay dead letters", // Brief description of the command.
+		Long: `Publish the selected poison messages again to the topic they were consumed from, with the configured broker.
+Replayed rows are kept and marked as replayed; only rows never replayed are selected unless --includeReplayed is set.`, // Detailed description of the command.
+		RunE: runDeadLetterReplay, // Function to execute when the command is run.
+	}
+)
+
+// DeadLetterCmd returns the "deadletter" command to be registered with the root command.
+//
+// Behavior:
+//   - Registers the "list" and "replay" subcommands with their filter and output flags.
+func DeadLetterCmd() *cobra.Command {
+	deadLetterListFilter.register(deadLetterListCmd, 100)
+	deadLetterListCmd.Flags().StringVarP(&deadLetterListOutput, "output", "o", "table", "Output format: table or json")
+
+	deadLetterReplayFilter.register(deadLetterReplayCmd, 1000)
+	deadLetterReplayCmd.Flags().StringVar(&deadLetterReplayToTopic, "toTopic", "", "Topic to publish to instead of the topic each message was consumed from")
+	deadLetterReplayCmd.Flags().BoolVar(&deadLetterReplayDryRun, "dryRun", false, "Only show what would be replayed")
+
+	deadLetterCmd.AddCommand(deadLetterListCmd)
+	deadLetterCmd.AddCommand(deadLetterReplayCmd)
+	return deadLetterCmd
+}
+
+// deadLetterFilterFlags holds the raw values of the dead letter filter flags of a command.
+type deadLetterFilterFlags struct {
+	ids             []string // Accepted dead letter ids.
+	topics          []string // Accepted topics.
+	createdFrom     string   // Lower bound of the dead-letter time.
+	createdTo       string   // Upper bound of the dead-letter time.
+	includeReplayed bool     // Also select the replayed messages.
+	limit           int      // Maximum number of rows selected.
+}
+
+// register defines the filter flags on the given command.
+//
+// Parameters:
+//   - c: The command receiving the flags.
+//   - limit: The default of the --limit flag.
+func (f *deadLetterFilterFlags) register(c *cobra.Command, limit int) {
+	c.Flags().StringSliceVar(&f.ids, "id", nil, "Dead letter ids to select")
+	c.Flags().StringSliceVar(&f.topics, "topic", nil, "Topics the messages were consumed from")
+	c.Flags().StringVar(&f.createdFrom, "createdFrom", "", "Select messages dead-lettered at or after this time (RFC3339 or a duration ago, e.g. 30m)")
+	c.Flags().StringVar(&f.createdTo, "createdTo", "", "Select messages dead-lettered before this time (RFC3339 or a duration ago, e.g. 30m)")
+	c.Flags().BoolVar(&f.includeReplayed, "includeReplayed", false, "Also select the messages already replayed")
+	c.Flags().IntVar(&f.limit, "limit", limit, "Maximum number of messages to select (0 for no limit)")
+}
+
+// filter converts the flag values into a dead letter filter.
+//
+// Returns:
+//   - The filter selecting the rows described by the flags.
+//   - An error if a time flag cannot be parsed.
+func (f *deadLetterFilterFlags) filter() (services.DeadLetterFilter, error) {
+	filter := services.DeadLetterFilter{
+		IDs:             f.ids,
+		Topics:          f.topics,
+		IncludeReplayed: f.includeReplayed,
+		Limit:           f.limit,
+	}
+
+	now := time.Now()
+	var err error
+	if filter.CreatedFrom, err = parseTimeFlag(f.createdFrom, now); err != nil {
+		return services.DeadLetterFilter{}, fmt.Errorf("invalid --createdFrom: %w", err)
+	}
+	if filter.CreatedTo, err = parseTimeFlag(f.createdTo, now); err != nil {
+		return services.DeadLetterFilter{}, fmt.Errorf("invalid --createdTo: %w", err)
+	}
+	return filter, nil
+}
+
+// runDeadLetterList is the execution logic for the "deadletter list" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: Command-line arguments passed to the command.
+//
+// Behavior:
+//   - Queries the listener_dead_letter table with the filter flags.
+//   - Prints the dead letters with their failure reason and payload in the requested output format.
+//
+// Returns:
+//   - nil if the dead letters were printed.
+//   - An error object if the flags are invalid or the query fails.
+func runDeadLetterList(cmd *cobra.Command, args []string) error {
+	// Step 1: Build the filter from the flags.
+	filter, err := deadLetterListFilter.filter()
+	if err != nil {
+		return err
+	}
+
+	// Step 2: Query the dead letters.
+	letters, err := services.ListDeadLetters(cmd.Context(), appConfig, filter)
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Print the dead letters in the requested format.
+	switch deadLetterListOutput {
+	case "table":
+		return printDeadLettersTable(os.Stdout, letters)
+	case "json":
+		return printDeadLettersJSON(os.Stdout, letters)
+	default:
+		return fmt.Errorf("unknown output format %q (use table or json)", deadLetterListOutput)
+	}
+}
+
+// runDeadLetterReplay is the execution logic for the "deadletter replay" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: Command-line arguments passed to the command.
+//
+// Behavior:
+//   - Selects the dead letters with the filter flags and prints them.
+//   - Publishes them again unless --dryRun is set, then prints the outcome.
+//
+// Returns:
+//   - nil if the dead letters were replayed or the dry run was printed.
+//   - An error object if the flags are invalid, a query or a publish fails.
+func runDeadLetterReplay(cmd *cobra.Command, args []string) error {
+	// Step 1: Build the filter from the flags.
+	filter, err := deadLetterReplayFilter.filter()
+	if err != nil {
+		return err
+	}
+
+	// Step 2: Replay the dead letters, or only select them in dry-run mode.
+	result, err := services.ReplayDeadLetters(cmd.Context(), appConfig, filter, deadLetterReplayToTopic, deadLetterReplayDryRun)
+	printDeadLettersTable(os.Stdout, result.Selected)
+	if err != nil {
+		fmt.Printf("\nReplayed %d of %d message(s) before the failure\n", result.Replayed, len(result.Selected))
+		return err
+	}
+
+	// Step 3: Print the outcome.
+	if deadLetterReplayDryRun {
+		fmt.Printf("\nDry run: %d message(s) would be replayed\n", len(result.Selected))
+		return nil
+	}
+	fmt.Printf("\nReplayed %d of %d message(s)\n", result.Replayed, len(result.Selected))
+	return nil
+}
+
+// deadLetterRecord is the JSON representation of a dead letter with its decoded payload.
+type deadLetterRecord struct {
+	services.DeadLetter
+	Payload any `json:"payload"` // Decoded message payload.
+}
+
+// printDeadLettersTable prints the dead letters as an aligned text table.
+func printDeadLettersTable(w io.Writer, letters []services.DeadLetter) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tMESSAGE\tTOPIC\tHANDLER\tREPLAYS\tCREATED\tREASON\tPAYLOAD")
+	for _, letter := range letters {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			letter.DeadLetterID, letter.MessageUUID, letter.Topic, letter.HandlerName, letter.ReplayCount,
+			letter.CreatedTimeUTC.Format(time.RFC3339), truncate(letter.Reason, 60),
+			truncate(messageText(letter.DecodedPayload()), 80))
+	}
+	fmt.Fprintf(tw, "\n%d message(s)\n", len(letters))
+	return tw.Flush()
+}
+
+// printDeadLettersJSON prints the dead letters as an indented JSON array.
+func printDeadLettersJSON(w io.Writer, letters []services.DeadLetter) error {
+	records := make([]deadLetterRecord, 0, len(letters))
+	for _, letter := range letters {
+		records = append(records, deadLetterRecord{DeadLetter: letter, Payload: letter.DecodedPayload()})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
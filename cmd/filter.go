@@ -0,0 +1,112 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the outbox row filter flags shared by the commands querying the outbox tables.
+package cmd
+
+import (
+	"fmt"
+	"outbox/debugger/services"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// outboxFilterFlags holds the raw values of the outbox row filter flags of a command.
+type outboxFilterFlags struct {
+	tables        []int    // Table indexes to query.
+	ids           []string // Accepted event outbox ids.
+	statuses      []string // Accepted row statuses.
+	topics        []string // Accepted event topics.
+	eventKeys     []string // Accepted event keys.
+	eventGroups   []string // Accepted event groups.
+	minRetry      int      // Minimum retry count, -1 when unset.
+	maxRetry      int      // Maximum retry count, -1 when unset.
+	createdFrom   string   // Lower bound of the creation time.
+	createdTo     string   // Upper bound of the creation time.
+	nextRetryFrom string   // Lower bound of the next retry time.
+	nextRetryTo   string   // Upper bound of the next retry time.
+}
+
+// register defines the filter flags on the given command.
+//
+// Parameters:
+//   - c: The command receiving the flags.
+func (f *outboxFilterFlags) register(c *cobra.Command) {
+	c.Flags().IntSliceVar(&f.tables, "table", nil, "Outbox table indexes to query (default: all tables)")
+	c.Flags().StringSliceVar(&f.ids, "id", nil, "Event outbox ids to select")
+	c.Flags().StringSliceVar(&f.statuses, "status", nil, "Row statuses to select")
+	c.Flags().StringSliceVar(&f.topics, "topic", nil, "Event topics to select")
+	c.Flags().StringSliceVar(&f.eventKeys, "eventKey", nil, "Event keys to select")
+	c.Flags().StringSliceVar(&f.eventGroups, "eventGroup", nil, "Event groups to select")
+	c.Flags().IntVar(&f.minRetry, "minRetry", -1, "Minimum retry count (default: no minimum)")
+	c.Flags().IntVar(&f.maxRetry, "maxRetry", -1, "Maximum retry count (default: no maximum)")
+	c.Flags().StringVar(&f.createdFrom, "createdFrom", "", "Select rows created at or after this time (RFC3339 or a duration ago, e.g. 30m)")
+	c.Flags().StringVar(&f.createdTo, "createdTo", "", "Select rows created before this time (RFC3339 or a duration ago, e.g. 30m)")
+	c.Flags().StringVar(&f.nextRetryFrom, "nextRetryFrom", "", "Select rows due at or after this time (RFC3339 or a duration ago, e.g. 30m)")
+	c.Flags().StringVar(&f.nextRetryTo, "nextRetryTo", "", "Select rows due before this time (RFC3339 or a duration ago, e.g. 30m)")
+}
+
+// filter converts the flag values into an outbox row filter.
+//
+// Returns:
+//   - The filter selecting the rows described by the flags.
+//   - An error if a time flag cannot be parsed.
+func (f *outboxFilterFlags) filter() (services.OutboxFilter, error) {
+	filter := services.OutboxFilter{
+		Tables:      f.tables,
+		IDs:         f.ids,
+		Statuses:    f.statuses,
+		Topics:      f.topics,
+		EventKeys:   f.eventKeys,
+		EventGroups: f.eventGroups,
+	}
+	if f.minRetry >= 0 {
+		filter.MinRetryCount = &f.minRetry
+	}
+	if f.maxRetry >= 0 {
+		filter.MaxRetryCount = &f.maxRetry
+	}
+
+	// Parse every time bound into its filter field.
+	now := time.Now()
+	for _, bound := range []struct {
+		flag  string
+		value string
+		dest  *time.Time
+	}{
+		{"createdFrom", f.createdFrom, &filter.CreatedFrom},
+		{"createdTo", f.createdTo, &filter.CreatedTo},
+		{"nextRetryFrom", f.nextRetryFrom, &filter.NextRetryFrom},
+		{"nextRetryTo", f.nextRetryTo, &filter.NextRetryTo},
+	} {
+		t, err := parseTimeFlag(bound.value, now)
+		if err != nil {
+			return services.OutboxFilter{}, fmt.Errorf("invalid --%s: %w", bound.flag, err)
+		}
+		*bound.dest = t
+	}
+
+	return filter, nil
+}
+
+// parseTimeFlag parses an absolute RFC3339 time or a duration relative to now.
+//
+// Parameters:
+//   - value: The flag value; an empty value yields the zero time.
+//   - now: The reference time for relative durations.
+//
+// Returns:
+//   - The parsed time; a duration such as "30m" means 30 minutes before now.
+//   - An error if the value is neither an RFC3339 time nor a duration.
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC3339 time nor a duration", value)
+	}
+	return now.Add(-d), nil
+}
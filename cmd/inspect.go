@@ -0,0 +1,162 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the "inspect" command for listing the rows stored in the outbox tables.
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"outbox/debugger/services"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for the "inspect" command
+	inspectFilter outboxFilterFlags // Row filter flags.
+	inspectOutput string            // Output format: table, json or csv.
+	inspectLimit  int               // Maximum number of rows printed.
+)
+
+var (
+	// inspectCmd defines the "inspect" command for querying the outbox tables.
+	inspectCmd = &cobra.Command{
+		Use:   "inspect",                                                              // Command usage text.
+		Short: "Inspect outbox rows",                                                  // Brief description of the command.
+		Long:  "List the rows of the event_outbox tables matching the given filters.", // Detailed description of the command.
+		RunE:  runInspect,                                                             // Function to execute when the command is run.
+	}
+)
+
+// InspectCmd returns the "inspect" command to be registered with the root command.
+//
+// Behavior:
+//   - Defines the row filter flags and the output flags.
+//   - Executes the runInspect function when invoked.
+func InspectCmd() *cobra.Command {
+	inspectFilter.register(inspectCmd)
+	inspectCmd.Flags().StringVarP(&inspectOutput, "output", "o", "table", "Output format: table, json or csv")
+	inspectCmd.Flags().IntVar(&inspectLimit, "limit", 100, "Maximum number of rows to print (0 for no limit)")
+	return inspectCmd
+}
+
+// runInspect is the execution logic for the "inspect" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: Command-line arguments passed to the command.
+//
+// Behavior:
+//   - Queries the selected outbox tables with the filter flags.
+//   - Prints the rows in the requested output format with the event message decoded.
+//
+// Returns:
+//   - nil if the rows were printed.
+//   - An error object if the flags are invalid or the query fails.
+func runInspect(cmd *cobra.Command, args []string) error {
+	// Step 1: Build the filter from the flags.
+	filter, err := inspectFilter.filter()
+	if err != nil {
+		return err
+	}
+	filter.Limit = inspectLimit
+
+	// Step 2: Query the outbox tables.
+	rows, err := services.InspectOutbox(cmd.Context(), appConfig, filter)
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Print the rows in the requested format.
+	switch inspectOutput {
+	case "table":
+		return printRowsTable(os.Stdout, rows)
+	case "json":
+		return printRowsJSON(os.Stdout, rows)
+	case "csv":
+		return printRowsCSV(os.Stdout, rows)
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or csv)", inspectOutput)
+	}
+}
+
+// inspectRecord is the JSON representation of an outbox row with its decoded event message.
+type inspectRecord struct {
+	services.OutboxRow
+	EventMessage any `json:"eventMessage"` // Decoded event payload.
+}
+
+// printRowsTable prints the rows as an aligned text table.
+func printRowsTable(w io.Writer, rows []services.OutboxRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tID\tSTATUS\tTOPIC\tKEY\tGROUP\tRETRY\tNEXT RETRY\tCREATED\tMESSAGE")
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			row.TableIndex, row.EventOutboxID, row.Status, row.EventTopic, row.EventKey, row.EventGroup,
+			row.RetryCount, row.NextRetryTimeUTC.Format(time.RFC3339), row.CreatedTimeUTC.Format(time.RFC3339),
+			truncate(messageText(row.DecodedMessage()), 80))
+	}
+	fmt.Fprintf(tw, "\n%d row(s)\n", len(rows))
+	return tw.Flush()
+}
+
+// printRowsJSON prints the rows as an indented JSON array.
+func printRowsJSON(w io.Writer, rows []services.OutboxRow) error {
+	records := make([]inspectRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, inspectRecord{OutboxRow: row, EventMessage: row.DecodedMessage()})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// printRowsCSV prints the rows as CSV with a header line.
+func printRowsCSV(w io.Writer, rows []services.OutboxRow) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{
+		"table_index", "event_outbox_id", "event_group", "event_topic", "event_key", "event_message", "retry_count",
+		"last_retry_time_utc", "next_retry_time_utc", "status", "hash_value1", "created_time_utc", "updated_time_utc", "row_version",
+	})
+	for _, row := range rows {
+		writer.Write([]string{
+			strconv.Itoa(row.TableIndex), row.EventOutboxID, row.EventGroup, row.EventTopic, row.EventKey, messageText(row.DecodedMessage()),
+			strconv.Itoa(row.RetryCount), row.LastRetryTimeUTC.Format(time.RFC3339Nano), row.NextRetryTimeUTC.Format(time.RFC3339Nano),
+			row.Status, row.HashValue1, row.CreatedTimeUTC.Format(time.RFC3339Nano), row.UpdatedTimeUTC.Format(time.RFC3339Nano), row.RowVersion,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// messageText returns a decoded event message or payload on a single line.
+func messageText(decoded any) string {
+	switch msg := decoded.(type) {
+	case json.RawMessage:
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, msg); err == nil {
+			return compact.String()
+		}
+		return string(msg)
+	case string:
+		return msg
+	default:
+		return fmt.Sprint(msg)
+	}
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -4,7 +4,10 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"outbox/debugger/helper"
 	"outbox/debugger/services"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -14,6 +17,14 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	// Flags for the "listen" command
+	listenExpect         int           // Number of events the publisher produced, 0 when unknown.
+	listenReportInterval time.Duration // Interval of the live latency report, 0 to disable it.
+	listenOrdered        bool          // Subscribe with message ordering and validate the order per ordering key.
+	listenTopics         []string      // Topics to subscribe to.
+)
+
 var (
 	// listenerCmd defines the "listen" command for starting listener services.
 	listenerCmd = &cobra.Command{
@@ -28,8 +39,13 @@ var (
 //
 // Behavior:
 //   - Defines the "listen" command for processing incoming messages.
+//   - Defines the flags for the topics, the number of expected events, the live latency report and the ordered mode.
 //   - Configures message routing, plugins, and middleware.
 func ListenerCmd() *cobra.Command {
+	listenerCmd.Flags().IntVar(&listenExpect, "expect", 0, "Number of events the publisher produced; stops once all were delivered (default: run until interrupted)")
+	listenerCmd.Flags().DurationVar(&listenReportInterval, "reportInterval", 10*time.Second, "Interval of the live latency report (0 disables it)")
+	listenerCmd.Flags().StringSliceVar(&listenTopics, "topics", nil, "Comma-separated topics to subscribe to (default: every outbox topic)")
+	listenerCmd.Flags().BoolVar(&listenOrdered, "ordered", false, "Subscribe with message ordering enabled and report every per-key sequence inversion")
 	return listenerCmd
 }
 
@@ -41,8 +57,14 @@ func ListenerCmd() *cobra.Command {
 //
 // Behavior:
 //   - Initializes a Watermill router with plugins and middleware.
-//   - Registers handlers for processing messages using the SubOutboxDebugger function.
-//   - Runs the router in a background context.
+//   - Registers a handler per topic of --topics (every outbox topic by default) using the SubOutboxDebugger function,
+//     each with the throttle, retry and timeout middleware of listener.middleware and listener.handlers.
+//   - Runs the router in a background context until SIGINT/SIGTERM or, with --expect, until every expected event was delivered.
+//   - Prints the commit→publish and commit→receive latencies every reportInterval while running.
+//   - With --ordered (or pubsub.messageOrdering), subscribes with message ordering enabled and checks that every
+//     ordering key is delivered in strictly increasing sequence order.
+//   - With listener.idempotency, skips the messages already processed and reports them per topic.
+//   - Prints the missing, duplicated and out-of-order deliveries per topic and ordering key, the order inversions and the latency summary.
 //
 // Returns:
 //   - nil if the router runs successfully.
@@ -51,28 +73,86 @@ func runListenerServices(cmd *cobra.Command, args []string) error {
 	// Step 1: Initialize the logger for Watermill.
 	logger := watermill.NewStdLogger(false, false)
 
-	// Step 2: Create a new router for message handling.
+	// Step 2: Create a new router with plugins and middleware for message handling.
+	router := newListenerRouter(logger)
+
+	// Step 3: Register message handlers.
+	opts := services.ListenerOptions{
+		Topics:  listenTopics,
+		Tracker: services.NewDeliveryTracker(listenExpect),
+		Latency: services.NewLatencyRecorder(),
+	}
+	if listenOrdered {
+		appConfig.PubSub.MessageOrdering = true
+	}
+	if appConfig.PubSub.MessageOrdering {
+		opts.Ordering = services.NewOrderValidator()
+	}
+	idempotency, err := services.NewListenerIdempotency(appConfig)
+	if err != nil {
+		return err
+	}
+	opts.Idempotency = idempotency
+	services.SubOutboxDebugger(appConfig, router, logger, opts)
+
+	// Step 4: Stop the router once every expected event was delivered.
+	go func() {
+		<-opts.Tracker.Done()
+		router.Close()
+	}()
+
+	// Step 5: Run the router in a background context, reporting the latencies periodically, then close the idempotency store.
+	ctx, stopReport := context.WithCancel(context.Background())
+	go reportLatencyPeriodically(ctx, os.Stdout, opts.Latency, listenReportInterval)
+	if err := router.Run(ctx); err != nil {
+		log.Error().Msgf("Recover Event Message With Error: %v", err)
+	}
+	stopReport()
+	closeListenerIdempotency(idempotency)
+
+	// Step 6: Print the delivery and latency reports.
+	printListenerReports(os.Stdout, opts)
+
+	// Step 7: Return nil to indicate successful execution.
+	return nil
+}
+
+// newListenerRouter creates the Watermill router used by the listener.
+//
+// Parameters:
+//   - logger: The Watermill logger used by the router.
+//
+// Behavior:
+//   - Adds the signals handler plugin and the correlation ID and recoverer middleware.
+//
+// Error Handling:
+//   - Logs a fatal error and terminates the program if the router cannot be created.
+func newListenerRouter(logger watermill.LoggerAdapter) *message.Router {
+	// Step 1: Create a new router for message handling.
 	router, err := message.NewRouter(message.RouterConfig{}, logger)
 	if err != nil {
 		log.Fatal().Msgf("could not create router: %v", err)
 	}
 
-	// Step 3: Add plugins and middleware to the router.
+	// Step 2: Add plugins and middleware to the router.
 	router.AddPlugin(plugin.SignalsHandler) // Gracefully handles shutdown signals.
 	router.AddMiddleware(
 		middleware.CorrelationID, // Copies correlation ID to outgoing messages.
 		middleware.Recoverer,     // Recovers from panics and passes errors to retry middleware.
 	)
 
-	// Step 4: Register message handlers.
-	services.SubOutboxDebugger(router, logger)
+	return router
+}
 
-	// Step 5: Run the router in a background context.
-	ctx := context.Background()
-	if err := router.Run(ctx); err != nil {
-		log.Error().Msgf("Recover Event Message With Error: %v", err)
+// closeListenerIdempotency releases the idempotency store of the listener once its router stopped.
+//
+// Parameters:
+//   - idempotency: The idempotency layer of the listener; nil has nothing to close.
+//
+// Error Handling:
+//   - Logs the error if the store cannot be closed; the listener has stopped anyway.
+func closeListenerIdempotency(idempotency *helper.Idempotency) {
+	if err := idempotency.Close(); err != nil {
+		log.Error().Msgf("Could not close the idempotency store: %v", err)
 	}
-
-	// Step 6: Return nil to indicate successful execution.
-	return nil
 }
@@ -0,0 +1,143 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the "offline" command, which runs the publisher, the cron relay and the listener in one process.
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"outbox/debugger/config"
+	"outbox/debugger/services"
+	"syscall"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for the "offline" command
+	offlineUseOutbox    bool   // Indicates whether to use the outbox pattern.
+	offlineMaxMsg       int    // Number of messages to publish, one per transaction.
+	offlineOrderingKey  string // Ordering key for message publishing.
+	offlineCallbackMode string // When the AfterAddEvent callbacks run.
+)
+
+var (
+	// offlineCmd defines the "offline" command for running the whole outbox flow in one process.
+	offlineCmd = &cobra.Command{
+		Use:   "offline",                                                                                      // Command usage text.
+		Short: "Publisher, cron relay and listener in one process",                                            // Brief description of the command.
+		Long:  "Run the publisher, the outbox cron relay and the listener together over an in-memory broker.", // Detailed description of the command.
+		RunE:  runOfflineServices,                                                                             // Function to execute when the command is run.
+	}
+)
+
+// OfflineCmd returns the "offline" command to be registered with the root command.
+//
+// Behavior:
+//   - Defines flags for message publishing (useOutbox, maxMsg, orderingKey, callbackMode).
+//   - Executes the runOfflineServices function when invoked.
+func OfflineCmd() *cobra.Command {
+	offlineCmd.Flags().BoolVar(&offlineUseOutbox, "useOutbox", true, "Use the outbox pattern")
+	offlineCmd.Flags().IntVar(&offlineMaxMsg, "maxMsg", 0, "Number of messages to publish, one per transaction")
+	offlineCmd.Flags().StringVar(&offlineOrderingKey, "orderingKey", "", "Ordering key value")
+	offlineCmd.Flags().StringVar(&offlineCallbackMode, "callbackMode", string(services.CallbackPerCommit), "When the AfterAddEvent callbacks run: perCommit, batch or skip (delivery by the cron relay only)")
+	return offlineCmd
+}
+
+// runOfflineServices is the execution logic for the "offline" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: Command-line arguments passed to the command.
+//
+// Behavior:
+//   - Switches the broker to the in-process Go channel, so only PostgreSQL is needed.
+//   - Starts the listener and waits until it is subscribed.
+//   - Starts the cron relay in the background.
+//   - Publishes maxMsg messages, then keeps the relay and the listener running until every message was
+//     delivered or until SIGINT/SIGTERM.
+//   - Prints the missing, duplicated and out-of-order deliveries per ordering key and the latency summary.
+//
+// Returns:
+//   - nil once the run was interrupted.
+//   - An error object if maxMsg is invalid or a component fails to start.
+func runOfflineServices(cmd *cobra.Command, args []string) error {
+	// Step 1: Validate the flags and select the in-memory broker.
+	if offlineMaxMsg <= 0 {
+		return errors.New("maxMsg must be greater than 0")
+	}
+	publishOpts := services.PublishOptions{
+		UseOutbox:       offlineUseOutbox,
+		OrderingKeys:    []string{offlineOrderingKey},
+		KeyDistribution: services.KeyRoundRobin,
+		MaxMsg:          offlineMaxMsg,
+		EventsPerTx:     1,
+		CallbackMode:    services.CallbackMode(offlineCallbackMode),
+		Workers:         1,
+		SerializeKeys:   true,
+	}
+	if err := publishOpts.Validate(appConfig); err != nil {
+		return err
+	}
+	if appConfig.Broker.Type != config.BrokerGoChannel {
+		log.Info().Msgf("[Offline] Using the %s broker instead of %s", config.BrokerGoChannel, appConfig.Broker.Type)
+		appConfig.Broker.Type = config.BrokerGoChannel
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Step 2: Start the listener and wait until it is subscribed.
+	logger := watermill.NewStdLogger(false, false)
+	router := newListenerRouter(logger)
+	idempotency, err := services.NewListenerIdempotency(appConfig)
+	if err != nil {
+		return err
+	}
+	opts := services.ListenerOptions{
+		Tracker:     services.NewDeliveryTracker(offlineMaxMsg),
+		Latency:     services.NewLatencyRecorder(),
+		Idempotency: idempotency,
+	}
+	services.SubOutboxDebugger(appConfig, router, logger, opts)
+
+	routerErr := make(chan error, 1)
+	go func() {
+		routerErr <- router.Run(ctx)
+	}()
+	select {
+	case <-router.Running():
+	case err := <-routerErr:
+		return fmt.Errorf("listener stopped: %w", err)
+	}
+
+	// Step 3: Start the cron relay on the same broker.
+	if err := services.StartOutboxRelay(appConfig); err != nil {
+		return err
+	}
+
+	// Step 4: Publish the messages.
+	fmt.Printf("Publishing %d message(s) offline (use outbox: %v, ordering key: %q)...\n", offlineMaxMsg, offlineUseOutbox, offlineOrderingKey)
+	result := services.PubOutboxDebugger(ctx, appConfig, publishOpts)
+	printPublishResult(os.Stdout, result)
+	fmt.Println("Published; the cron relay and the listener keep running until every message is delivered or interrupted (Ctrl+C).")
+
+	// Step 5: Keep the relay and the listener running until every message was delivered or interrupted.
+	select {
+	case <-opts.Tracker.Done():
+		router.Close()
+	case <-ctx.Done():
+	}
+	if err := <-routerErr; err != nil {
+		log.Error().Msgf("Recover Event Message With Error: %v", err)
+	}
+	closeListenerIdempotency(idempotency)
+
+	// Step 6: Print the delivery and latency reports.
+	printListenerReports(os.Stdout, opts)
+
+	return nil
+}
@@ -3,18 +3,38 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"outbox/debugger/services"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Flags for the "publish" command
-	useOutbox   bool   // Indicates whether to use the outbox pattern.
-	maxMsg      int    // Maximum number of messages to publish.
-	orderingKey string // Ordering key for message publishing.
+	useOutbox       bool          // Indicates whether to use the outbox pattern.
+	publishTopics   []string      // Topics the events are spread over.
+	maxMsg          int           // Maximum number of transactions to run.
+	orderingKey     string        // Ordering key for message publishing.
+	orderingKeys    []string      // Ordering keys the events are spread over.
+	keyCount        int           // Number of ordering keys to generate.
+	keyPrefix       string        // Prefix of the generated ordering keys.
+	keyDistribution string        // How the events are spread over the ordering keys.
+	zipfSkew        float64       // Skew of the zipf key distribution.
+	eventsPerTx     int           // Number of events added per transaction.
+	rollbackRate    float64       // Fraction of transactions deliberately rolled back.
+	callbackMode    string        // When the AfterAddEvent callbacks run.
+	workers         int           // Number of concurrent publishing goroutines.
+	serializeKeys   bool          // Run the transactions of a topic and ordering key one at a time.
+	rate            float64       // Maximum number of events per second.
+	duration        time.Duration // Maximum duration of the run.
 )
 
 var (
@@ -25,8 +45,21 @@ var (
 		Long: `Publish messages using various options. 
 Flags:
   -useOutbox        Use the outbox pattern (default: true)
-  -maxMsg           Maximum number of messages to publish (default: 0)
-  -orderingKey      Ordering key for messages (default: not use ordering key)`,
+  -topics           Comma-separated topics the events are spread over in turn (default: every outbox topic)
+  -maxMsg           Maximum number of transactions to run (default: 0, no limit when --duration is set)
+  -orderingKey      Ordering key for messages (default: not use ordering key)
+  -orderingKeys     Comma-separated ordering keys the events are spread over
+  -keyCount         Number of ordering keys to generate, named <keyPrefix>-N (default: 0)
+  -keyPrefix        Prefix of the generated ordering keys (default: key)
+  -keyDistribution  How the events are spread over the keys: roundRobin, uniform or zipf (default: roundRobin)
+  -zipfSkew         Skew of the zipf distribution, greater than 1; higher makes the first keys hotter (default: 1.1)
+  -eventsPerTx      Number of events added per transaction (default: 1)
+  -rollbackRate     Fraction of transactions rolled back after adding their events (default: 0)
+  -callbackMode     When the AfterAddEvent callbacks run: perCommit, batch or skip (default: perCommit)
+  -workers          Number of concurrent publishing goroutines (default: 1)
+  -serializeKeys    Run the transactions sharing a topic and ordering key one at a time, numbered and published in commit order (default: false)
+  -rate             Maximum number of events per second across all workers (default: 0, no limit)
+  -duration         Maximum duration of the run (default: 0, no limit when --maxMsg is set)`,
 		Run: func(c *cobra.Command, args []string) {
 			runPublisherServices() // Executes the publishing logic.
 		},
@@ -36,42 +69,169 @@ Flags:
 // PublisherCmd returns the "publish" command to be registered with the root command.
 //
 // Behavior:
-//   - Defines flags for message publishing (useOutbox, maxMsg, orderingKey).
+//   - Defines flags for message publishing (useOutbox, topics, maxMsg), ordering keys (orderingKey, orderingKeys, keyCount,
+//     keyPrefix, keyDistribution, zipfSkew), transactions (eventsPerTx, rollbackRate, callbackMode)
+//     and load generation (workers, serializeKeys, rate, duration).
 //   - Executes the runPublisherServices function when invoked.
 func PublisherCmd() *cobra.Command {
 	// Define flags for the publish command
 	publisherCmd.Flags().BoolVar(&useOutbox, "useOutbox", true, "Use the outbox pattern")
-	publisherCmd.Flags().IntVar(&maxMsg, "maxMsg", 0, "Number of messages to publish")
+	publisherCmd.Flags().StringSliceVar(&publishTopics, "topics", nil, "Comma-separated topics the events are spread over in turn (default: every outbox topic)")
+	publisherCmd.Flags().IntVar(&maxMsg, "maxMsg", 0, "Number of transactions to run, each adding --eventsPerTx events")
 	publisherCmd.Flags().StringVar(&orderingKey, "orderingKey", "", "Ordering key value")
+	publisherCmd.Flags().StringSliceVar(&orderingKeys, "orderingKeys", nil, "Comma-separated ordering keys the events are spread over")
+	publisherCmd.Flags().IntVar(&keyCount, "keyCount", 0, "Number of ordering keys to generate, named <keyPrefix>-N")
+	publisherCmd.Flags().StringVar(&keyPrefix, "keyPrefix", "key", "Prefix of the generated ordering keys")
+	publisherCmd.Flags().StringVar(&keyDistribution, "keyDistribution", string(services.KeyRoundRobin), "How the events are spread over the ordering keys: roundRobin, uniform or zipf (hot keys)")
+	publisherCmd.Flags().Float64Var(&zipfSkew, "zipfSkew", services.DefaultZipfSkew, "Skew of the zipf key distribution, greater than 1; higher makes the first keys hotter")
+	publisherCmd.Flags().IntVar(&eventsPerTx, "eventsPerTx", 1, "Number of events added per transaction")
+	publisherCmd.Flags().Float64Var(&rollbackRate, "rollbackRate", 0, "Fraction of transactions deliberately rolled back after adding their events (0 to 1)")
+	publisherCmd.Flags().StringVar(&callbackMode, "callbackMode", string(services.CallbackPerCommit), "When the AfterAddEvent callbacks run: perCommit (after each commit), batch (after the run) or skip (never, leaving delivery to the cron relay)")
+	publisherCmd.Flags().IntVar(&workers, "workers", 1, "Number of concurrent publishing goroutines")
+	publisherCmd.Flags().BoolVar(&serializeKeys, "serializeKeys", false, "Run the transactions sharing a topic and ordering key one at a time, so their events are numbered and published in commit order")
+	publisherCmd.Flags().Float64Var(&rate, "rate", 0, "Maximum number of events per second across all workers (0 for no limit)")
+	publisherCmd.Flags().DurationVar(&duration, "duration", 0, "Maximum duration of the run (0 for no limit)")
 	return publisherCmd
 }
 
 // runPublisherServices executes the logic for publishing messages.
 //
 // Behavior:
-//   - Reads configuration flags (useOutbox, maxMsg, orderingKey).
-//   - Validates input flags and ensures maxMsg is greater than 0.
-//   - Calls the PubOutboxDebugger function to publish messages.
+//   - Reads configuration flags (useOutbox, topics, maxMsg, the ordering key flags, eventsPerTx, rollbackRate, callbackMode, workers, serializeKeys, rate, duration).
+//   - Validates input flags and ensures the run is bounded by maxMsg or duration.
+//   - Calls the PubOutboxDebugger function to publish messages until done or SIGINT/SIGTERM.
 //
 // Error Handling:
-//   - Exits with an error message if maxMsg is invalid.
+//   - Exits with an error message if the flags are invalid.
 //
 // Output:
 //   - Logs the settings and progress of the publishing process.
+//   - Prints the achieved throughput, the transaction latency and the error counts.
 func runPublisherServices() {
 	fmt.Printf("Running Publisher Services with settings:\n")
 	fmt.Printf("  Use Outbox: %v\n", useOutbox)
-	fmt.Printf("  Max Messages: %d\n", maxMsg)
+	fmt.Printf("  Topics: %v\n", publishTopics)
+	fmt.Printf("  Max Transactions: %d\n", maxMsg)
 	fmt.Printf("  Ordering Key: %s\n", orderingKey)
+	fmt.Printf("  Ordering Keys: %v (%d generated with prefix %q)\n", orderingKeys, keyCount, keyPrefix)
+	fmt.Printf("  Key Distribution: %s (zipf skew %g)\n", keyDistribution, zipfSkew)
+	fmt.Printf("  Events Per Transaction: %d\n", eventsPerTx)
+	fmt.Printf("  Rollback Rate: %g\n", rollbackRate)
+	fmt.Printf("  Callback Mode: %s\n", callbackMode)
+	fmt.Printf("  Workers: %d\n", workers)
+	fmt.Printf("  Serialize Keys: %v\n", serializeKeys)
+	fmt.Printf("  Rate: %g events/s\n", rate)
+	fmt.Printf("  Duration: %s\n", duration)
 
-	// Validate maxMsg flag
-	if maxMsg <= 0 {
-		fmt.Println("Error: maxMsg must be greater than 0")
+	// Validate the flags with the checks shared with the scenario publish phases
+	keys, err := services.ResolveOrderingKeys(orderingKey, orderingKeys, keyCount, keyPrefix)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1) // Exit the application with an error status.
+	}
+	opts := services.PublishOptions{
+		UseOutbox:       useOutbox,
+		Topics:          publishTopics,
+		OrderingKeys:    keys,
+		KeyDistribution: services.KeyDistribution(keyDistribution),
+		ZipfSkew:        zipfSkew,
+		MaxMsg:          maxMsg,
+		EventsPerTx:     eventsPerTx,
+		RollbackRate:    rollbackRate,
+		CallbackMode:    services.CallbackMode(callbackMode),
+		Workers:         workers,
+		SerializeKeys:   serializeKeys,
+		Rate:            rate,
+		Duration:        duration,
+	}
+	if err := opts.Validate(appConfig); err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1) // Exit the application with an error status.
 	}
 
-	// Publishing messages
+	// Publishing messages until done or interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	fmt.Println("Publishing messages...")
-	services.PubOutboxDebugger(useOutbox, orderingKey, maxMsg) // Call the service to publish messages.
+	result := services.PubOutboxDebugger(ctx, appConfig, opts) // Call the service to publish messages.
+	printPublishResult(os.Stdout, result)
 	fmt.Println("Done!")
 }
+
+// printPublishResult prints the outcome of a publish run.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - result: The outcome to print.
+func printPublishResult(w io.Writer, result services.PublishResult) {
+	// Step 1: Print the throughput and the transaction latency.
+	fmt.Fprintf(w, "Publish report for run %s\n", result.RunID)
+	fmt.Fprintf(w, "  Transactions: %d attempted, %d committed, %d rolled back, %d failed in %s\n",
+		result.Attempted, result.Committed, result.RolledBack, result.Failed, result.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "  Events: %d committed (expect these in the listener), %d rolled back (must never be delivered)\n", result.Events, result.Doomed)
+	fmt.Fprintf(w, "  Callbacks run: %d\n", result.Callbacks)
+	if result.Skipped > 0 {
+		fmt.Fprintf(w, "  Sequence numbers skipped by failed transactions: %d (reported missing by the listener; use --serializeKeys to avoid them)\n", result.Skipped)
+	}
+	fmt.Fprintf(w, "  Throughput: %.1f committed tx/s, %.1f committed events/s\n", result.Throughput(), result.EventsThroughput())
+	fmt.Fprintf(w, "  Transaction latency: p50 %s, p90 %s, p99 %s, max %s\n",
+		roundLatency(result.TxLatency.P50), roundLatency(result.TxLatency.P90), roundLatency(result.TxLatency.P99), roundLatency(result.TxLatency.Max))
+
+	// Step 2: Print the busiest topics and ordering keys when the events were spread over several.
+	if len(result.TopicEvents) > 1 {
+		printKeyEvents(w, "topic", result.TopicEvents, result.Events)
+	}
+	if len(result.KeyEvents) > 1 {
+		printKeyEvents(w, "ordering key", result.KeyEvents, result.Events)
+	}
+
+	// Step 3: Print the errors, most frequent first.
+	if len(result.Errors) == 0 {
+		return
+	}
+	messages := make([]string, 0, len(result.Errors))
+	for msg := range result.Errors {
+		messages = append(messages, msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return result.Errors[messages[i]] > result.Errors[messages[j]] })
+	fmt.Fprintln(w, "  Errors:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, msg := range messages {
+		fmt.Fprintf(tw, "    %d\t%s\n", result.Errors[msg], msg)
+	}
+	tw.Flush()
+}
+
+// maxPrintedKeys is the number of topics or ordering keys listed in the publish report.
+const maxPrintedKeys = 10
+
+// printKeyEvents prints the number of committed events of the busiest topics or ordering keys.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - label: What the keys are, "topic" or "ordering key".
+//   - keyEvents: The number of committed events per key.
+//   - total: The number of committed events.
+func printKeyEvents(w io.Writer, label string, keyEvents map[string]int, total int) {
+	keys := make([]string, 0, len(keyEvents))
+	for key := range keyEvents {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keyEvents[keys[i]] != keyEvents[keys[j]] {
+			return keyEvents[keys[i]] > keyEvents[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Fprintf(w, "  Events per %s (%d, busiest first):\n", label, len(keys))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, key := range keys[:min(len(keys), maxPrintedKeys)] {
+		fmt.Fprintf(tw, "    %s\t%d\t%.1f%%\n", key, keyEvents[key], 100*float64(keyEvents[key])/float64(max(1, total)))
+	}
+	tw.Flush()
+	if len(keys) > maxPrintedKeys {
+		fmt.Fprintf(w, "    ... %d more\n", len(keys)-maxPrintedKeys)
+	}
+}
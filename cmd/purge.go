@@ -0,0 +1,113 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the "purge" command for deleting or archiving old outbox rows.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"outbox/debugger/services"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for the "purge" command
+	purgeFilter    outboxFilterFlags // Row filter flags.
+	purgeOlderThan time.Duration     // Retention window of the rows.
+	purgeMode      string            // Delete or archive the rows.
+	purgeBatchSize int               // Maximum number of rows purged per batch.
+	purgeSleep     time.Duration     // Pause between two batches.
+	purgeDryRun    bool              // Only count the rows that would be purged.
+)
+
+var (
+	// purgeCmd defines the "purge" command for deleting or archiving old outbox rows.
+	purgeCmd = &cobra.Command{
+		Use:   "purge",                            // Command usage text.
+		Short: "Purge or archive old outbox rows", // Brief description of the command.
+		Long: `Delete, or move to event_outbox_archive, the outbox rows with the given statuses created before the retention window.
+Rows are processed in batches with a pause in between so the cron relay keeps access to the tables.`, // Detailed description of the command.
+		RunE: runPurge, // Function to execute when the command is run.
+	}
+)
+
+// PurgeCmd returns the "purge" command to be registered with the root command.
+//
+// Behavior:
+//   - Defines the row filter flags, the retention window and the batching flags.
+//   - Executes the runPurge function when invoked.
+func PurgeCmd() *cobra.Command {
+	purgeFilter.register(purgeCmd)
+	purgeCmd.Flags().DurationVar(&purgeOlderThan, "olderThan", 0, "Retention window: purge rows created more than this duration ago (required)")
+	purgeCmd.Flags().StringVar(&purgeMode, "mode", string(services.PurgeDelete), "Purge mode: delete or archive")
+	purgeCmd.Flags().IntVar(&purgeBatchSize, "batchSize", 500, "Maximum number of rows purged per batch")
+	purgeCmd.Flags().DurationVar(&purgeSleep, "sleep", 200*time.Millisecond, "Pause between two batches")
+	purgeCmd.Flags().BoolVar(&purgeDryRun, "dryRun", false, "Only count the rows that would be purged")
+	purgeCmd.MarkFlagRequired("status")
+	purgeCmd.MarkFlagRequired("olderThan")
+	return purgeCmd
+}
+
+// runPurge is the execution logic for the "purge" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: Command-line arguments passed to the command.
+//
+// Behavior:
+//   - Builds the row filter from the flags, bounded by the retention window.
+//   - Purges the rows batch by batch until done or interrupted by SIGINT/SIGTERM.
+//   - Prints the number of rows purged per table.
+//
+// Returns:
+//   - nil if the purge completed.
+//   - An error object if the flags are invalid, a statement fails or the purge was interrupted.
+func runPurge(cmd *cobra.Command, args []string) error {
+	// Step 1: Build the filter from the flags.
+	filter, err := purgeFilter.filter()
+	if err != nil {
+		return err
+	}
+	if purgeOlderThan <= 0 {
+		return fmt.Errorf("--olderThan must be greater than 0")
+	}
+	cutoff := time.Now().Add(-purgeOlderThan)
+	if filter.CreatedTo.IsZero() || filter.CreatedTo.After(cutoff) {
+		filter.CreatedTo = cutoff
+	}
+
+	// Step 2: Purge the rows, stopping between batches on SIGINT/SIGTERM.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := services.PurgeOutbox(ctx, appConfig, services.PurgeOptions{
+		Filter:    filter,
+		Mode:      services.PurgeMode(purgeMode),
+		BatchSize: purgeBatchSize,
+		Sleep:     purgeSleep,
+		DryRun:    purgeDryRun,
+	})
+
+	// Step 3: Print the rows purged per table, even after a failure.
+	verb := purgeMode + "d"
+	if purgeDryRun {
+		verb = "would be " + verb
+	}
+	indexes := make([]int, 0, len(result))
+	for index := range result {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var total int64
+	for _, index := range indexes {
+		fmt.Printf("  event_outbox%d: %d row(s) %s\n", index, result[index], verb)
+		total += result[index]
+	}
+	fmt.Printf("Total: %d row(s) %s\n", total, verb)
+
+	return err
+}
@@ -0,0 +1,244 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the reports printed by the commands running the listener.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"outbox/debugger/services"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// printListenerReports prints the reports of every observer of the listener.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - opts: The observers of the listener; nil observers are skipped.
+func printListenerReports(w io.Writer, opts services.ListenerOptions) {
+	if opts.Tracker != nil {
+		printDeliveryReport(w, opts.Tracker.Report())
+	}
+	if opts.Ordering != nil {
+		fmt.Fprintln(w)
+		printOrderingReport(w, opts.Ordering.Report())
+	}
+	if opts.Latency != nil {
+		fmt.Fprintln(w)
+		printLatencyReport(w, opts.Latency.Report())
+	}
+	if opts.Idempotency != nil {
+		fmt.Fprintln(w)
+		printIdempotencyReport(w, opts.Idempotency.Duplicates())
+	}
+}
+
+// printIdempotencyReport prints the duplicates skipped by the idempotency layer of the listener.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - duplicates: The number of duplicates skipped per topic.
+func printIdempotencyReport(w io.Writer, duplicates map[string]int64) {
+	topics := make([]string, 0, len(duplicates))
+	var total int64
+	for topic, count := range duplicates {
+		topics = append(topics, topic)
+		total += count
+	}
+	sort.Strings(topics)
+
+	fmt.Fprintln(w, "Idempotency report")
+	fmt.Fprintf(w, "  Duplicates skipped: %d\n", total)
+	for _, topic := range topics {
+		fmt.Fprintf(w, "  Topic %s: %d\n", topic, duplicates[topic])
+	}
+}
+
+// printDeliveryReport prints the deliveries seen by the listener.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - report: The report to print.
+func printDeliveryReport(w io.Writer, report services.DeliveryReport) {
+	// Step 1: Print the totals.
+	fmt.Fprintln(w, "Delivery report")
+	fmt.Fprintf(w, "  Deliveries: %d (%d unique events, %d with a checksum mismatch, %d rolled back)\n",
+		report.Delivered+report.Corrupted+len(report.Doomed), report.Unique, report.Corrupted, len(report.Doomed))
+	if report.Expected > 0 {
+		fmt.Fprintf(w, "  Expected events: %d (%d never delivered)\n", report.Expected, report.Unaccounted())
+	}
+
+	// Step 2: Print the anomalies per publisher run, topic and ordering key.
+	for _, key := range report.Keys {
+		fmt.Fprintf(w, "  Run %s, topic %s, ordering key %q: %d deliveries, %d unique, highest sequence %d\n", key.RunID, key.Topic, key.OrderingKey, key.Delivered, key.Unique, key.Highest)
+		if len(key.Missing) > 0 {
+			fmt.Fprintf(w, "    Missing (%d): %s\n", len(key.Missing), formatSequences(key.Missing))
+		}
+		if len(key.Duplicates) > 0 {
+			sequences := make([]int, 0, len(key.Duplicates))
+			for sequence := range key.Duplicates {
+				sequences = append(sequences, sequence)
+			}
+			sort.Ints(sequences)
+			parts := make([]string, len(sequences))
+			for i, sequence := range sequences {
+				parts[i] = fmt.Sprintf("%d(+%d)", sequence, key.Duplicates[sequence])
+			}
+			fmt.Fprintf(w, "    Duplicated (%d): %s\n", len(sequences), strings.Join(parts, ", "))
+		}
+		if len(key.OutOfOrder) > 0 {
+			parts := make([]string, len(key.OutOfOrder))
+			for i, delivery := range key.OutOfOrder {
+				parts[i] = fmt.Sprintf("%d after %d", delivery.Sequence, delivery.After)
+			}
+			fmt.Fprintf(w, "    Out of order (%d): %s\n", len(parts), strings.Join(parts, ", "))
+		}
+	}
+
+	// Step 3: Print the deliveries of rolled back events.
+	if len(report.Doomed) > 0 {
+		fmt.Fprintf(w, "  Rolled back events delivered (%d):\n", len(report.Doomed))
+		for _, doomed := range report.Doomed {
+			fmt.Fprintf(w, "    Run %s, topic %s, ordering key %q, doomed sequence %d\n", doomed.RunID, doomed.Topic, doomed.OrderingKey, doomed.Sequence)
+		}
+	}
+
+	// Step 4: Print the verdict.
+	if report.OK() {
+		fmt.Fprintln(w, "  Result: OK")
+	} else {
+		fmt.Fprintln(w, "  Result: delivery anomalies detected")
+	}
+}
+
+// maxPrintedInversions is the number of order inversions listed in the ordering report.
+const maxPrintedInversions = 100
+
+// printOrderingReport prints whether every ordering key was delivered in strictly increasing sequence order.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - report: The report to print.
+func printOrderingReport(w io.Writer, report services.OrderingReport) {
+	// Step 1: Print the totals.
+	redeliveries := 0
+	for _, inversion := range report.Inversions {
+		if inversion.Redelivery() {
+			redeliveries++
+		}
+	}
+	fmt.Fprintln(w, "Ordering report")
+	fmt.Fprintf(w, "  Deliveries: %d over %d topic and ordering key pair(s), %d inversion(s) (%d redeliveries of an already delivered event)\n",
+		report.Deliveries, report.Keys, len(report.Inversions), redeliveries)
+
+	// Step 2: Print the inversions with both deliveries.
+	for _, inversion := range report.Inversions[:min(len(report.Inversions), maxPrintedInversions)] {
+		fmt.Fprintf(w, "  Run %s, topic %s, ordering key %q: event %d (message %s, published %s, received %s) after event %d (message %s, published %s, received %s)\n",
+			inversion.RunID, inversion.Topic, inversion.OrderingKey,
+			inversion.Late.Sequence, inversion.Late.MessageID, formatTimestamp(inversion.Late.PublishedAt), formatTimestamp(inversion.Late.ReceivedAt),
+			inversion.After.Sequence, inversion.After.MessageID, formatTimestamp(inversion.After.PublishedAt), formatTimestamp(inversion.After.ReceivedAt))
+	}
+	if len(report.Inversions) > maxPrintedInversions {
+		fmt.Fprintf(w, "  ... %d more\n", len(report.Inversions)-maxPrintedInversions)
+	}
+
+	// Step 3: Print the verdict.
+	if report.Preserved() {
+		fmt.Fprintln(w, "  Result: order preserved")
+	} else {
+		fmt.Fprintln(w, "  Result: order NOT preserved")
+	}
+}
+
+// formatTimestamp formats a timestamp with microseconds, or "?" when it is unknown.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return "?"
+	}
+	return t.Format("15:04:05.000000")
+}
+
+// formatSequences formats sorted sequence numbers, collapsing consecutive runs into ranges (e.g. "3-7, 10").
+func formatSequences(sequences []int) string {
+	var parts []string
+	for i := 0; i < len(sequences); {
+		j := i
+		for j+1 < len(sequences) && sequences[j+1] == sequences[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(sequences[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sequences[i], sequences[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reportLatencyPeriodically prints the latency report every interval until the context is cancelled.
+//
+// Parameters:
+//   - ctx: The context stopping the reports.
+//   - w: The writer receiving the output.
+//   - recorder: The recorder providing the latencies.
+//   - interval: The interval between two reports; 0 disables the reports.
+func reportLatencyPeriodically(ctx context.Context, w io.Writer, recorder *services.LatencyRecorder, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			printLatencyReport(w, recorder.Report())
+		}
+	}
+}
+
+// printLatencyReport prints the commit→publish and commit→receive latencies per topic and per ordering key.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - report: The report to print.
+func printLatencyReport(w io.Writer, report services.LatencyReport) {
+	fmt.Fprintf(w, "Latency report at %s\n", time.Now().Format(time.RFC3339))
+	for _, section := range []struct {
+		title  string
+		groups []services.LatencyGroup
+	}{
+		{"TOPIC", report.Topics},
+		{"ORDERING KEY", report.Keys},
+	} {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "  %s\tLATENCY\tCOUNT\tP50\tP90\tP99\tMAX\n", section.title)
+		for _, group := range section.groups {
+			for _, latency := range []struct {
+				name  string
+				stats services.LatencyStats
+			}{
+				{"commit→publish", group.CommitToPublish},
+				{"commit→receive", group.CommitToReceive},
+			} {
+				fmt.Fprintf(tw, "  %q\t%s\t%d\t%s\t%s\t%s\t%s\n", group.Name, latency.name, latency.stats.Count,
+					roundLatency(latency.stats.P50), roundLatency(latency.stats.P90), roundLatency(latency.stats.P99), roundLatency(latency.stats.Max))
+			}
+		}
+		tw.Flush()
+	}
+}
+
+// roundLatency rounds a latency to a readable precision.
+func roundLatency(d time.Duration) time.Duration {
+	if d >= time.Second || d <= -time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,109 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the "requeue" command for resetting stuck outbox rows.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"outbox/debugger/services"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for the "requeue" command
+	requeueFilter      outboxFilterFlags // Row filter flags.
+	requeueOlderThan   time.Duration     // Minimum age of the selected rows.
+	requeueStatus      string            // Status written to the rows.
+	requeueRetryCount  int               // Retry count written to the rows.
+	requeueNextRetryIn time.Duration     // Delay until the next retry of the rows.
+	requeueLimit       int               // Maximum number of rows requeued.
+	requeueDryRun      bool              // Only show what would change.
+)
+
+var (
+	// requeueCmd defines the "requeue" command for resetting stuck outbox rows.
+	requeueCmd = &cobra.Command{
+		Use:   "requeue",                   // Command usage text.
+		Short: "Requeue stuck outbox rows", // Brief description of the command.
+		Long: `Reset the status, retry count and next retry time of outbox rows so the cron relay picks them up again.
+Rows are updated under row_version optimistic locking: rows changed by the relay after they were selected are skipped.`, // Detailed description of the command.
+		RunE: runRequeue, // Function to execute when the command is run.
+	}
+)
+
+// RequeueCmd returns the "requeue" command to be registered with the root command.
+//
+// Behavior:
+//   - Defines the row filter flags and the values written to the selected rows.
+//   - Executes the runRequeue function when invoked.
+func RequeueCmd() *cobra.Command {
+	requeueFilter.register(requeueCmd)
+	requeueCmd.Flags().DurationVar(&requeueOlderThan, "olderThan", 0, "Select rows created more than this duration ago")
+	requeueCmd.Flags().StringVar(&requeueStatus, "setStatus", "", "Status written to the requeued rows (required)")
+	requeueCmd.Flags().IntVar(&requeueRetryCount, "setRetryCount", 0, "Retry count written to the requeued rows")
+	requeueCmd.Flags().DurationVar(&requeueNextRetryIn, "nextRetryIn", 0, "Delay from now until the next retry of the requeued rows")
+	requeueCmd.Flags().IntVar(&requeueLimit, "limit", 1000, "Maximum number of rows to requeue (0 for no limit)")
+	requeueCmd.Flags().BoolVar(&requeueDryRun, "dryRun", false, "Only show what would change")
+	requeueCmd.MarkFlagRequired("setStatus")
+	return requeueCmd
+}
+
+// runRequeue is the execution logic for the "requeue" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: Command-line arguments passed to the command.
+//
+// Behavior:
+//   - Builds the row filter from the flags; --olderThan narrows the creation time.
+//   - Prints the before and after values of every selected row.
+//   - Requeues the rows unless --dryRun is set, then prints the outcome.
+//
+// Returns:
+//   - nil if the rows were requeued or the dry run was printed.
+//   - An error object if the flags are invalid or a query fails.
+func runRequeue(cmd *cobra.Command, args []string) error {
+	// Step 1: Build the filter from the flags.
+	filter, err := requeueFilter.filter()
+	if err != nil {
+		return err
+	}
+	if requeueOlderThan > 0 {
+		filter.CreatedTo = time.Now().Add(-requeueOlderThan)
+	}
+	filter.Limit = requeueLimit
+
+	// Step 2: Requeue the rows, or only select them in dry-run mode.
+	result, err := services.RequeueOutbox(cmd.Context(), appConfig, filter, services.RequeueChange{
+		Status:     requeueStatus,
+		RetryCount: requeueRetryCount,
+		Delay:      requeueNextRetryIn,
+	}, requeueDryRun)
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Print the planned change of every selected row.
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tID\tSTATUS\tRETRY\tNEXT RETRY")
+	for _, row := range result.Selected {
+		fmt.Fprintf(tw, "%d\t%s\t%s -> %s\t%d -> %d\t%s -> %s\n",
+			row.TableIndex, row.EventOutboxID, row.Status, requeueStatus, row.RetryCount, requeueRetryCount,
+			row.NextRetryTimeUTC.Format(time.RFC3339), result.NextRetryTimeUTC.Format(time.RFC3339))
+	}
+	tw.Flush()
+
+	// Step 4: Print the outcome.
+	if requeueDryRun {
+		fmt.Printf("\nDry run: %d row(s) would be requeued\n", len(result.Selected))
+		return nil
+	}
+	fmt.Printf("\nRequeued %d of %d row(s)\n", result.Requeued, len(result.Selected))
+	for _, row := range result.Conflicts {
+		fmt.Printf("  skipped %s in table %d: row_version changed since it was selected\n", row.EventOutboxID, row.TableIndex)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the "scenario" command, which runs scripted outbox experiments described in YAML files.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"outbox/debugger/config"
+	"outbox/debugger/services"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// scenarioCmd defines the "scenario" command grouping the scenario subcommands.
+	scenarioCmd = &cobra.Command{
+		Use:   "scenario",                                                                         // Command usage text.
+		Short: "Scripted outbox experiments",                                                      // Brief description of the command.
+		Long:  "Run outbox experiments described in scenario files and check their expectations.", // Detailed description of the command.
+	}
+
+	// scenarioRunCmd defines the "scenario run" command for running a scenario file.
+	scenarioRunCmd = &cobra.Command{
+		Use:   "run <file.yaml>",                                                                                  // Command usage text.
+		Short: "Run a scenario file",                                                                              // Brief description of the command.
+		Long:  "Run the phases of a scenario file, then check its expectations; exits non-zero if any one fails.", // Detailed description of the command.
+		Args:  cobra.ExactArgs(1),                                                                                 // The scenario file is required.
+		RunE:  runScenario,                                                                                        // Function to execute when the command is run.
+	}
+)
+
+// ScenarioCmd returns the "scenario" command to be registered with the root command.
+//
+// Behavior:
+//   - Registers the "run" subcommand executing a scenario file.
+func ScenarioCmd() *cobra.Command {
+	scenarioCmd.AddCommand(scenarioRunCmd)
+	return scenarioCmd
+}
+
+// scenarioRunner holds the state of a running scenario.
+type scenarioRunner struct {
+	scenario   *services.Scenario       // Scenario being run.
+	opts       services.ListenerOptions // Observers of the in-process listener.
+	configPath string                   // Configuration file handed to the cron relay process.
+	cron       *exec.Cmd                // Running cron relay process, nil when stopped.
+	runIDs     []string                 // Identifiers of the publisher runs of the scenario.
+	committed  int                      // Number of events committed by the publisher runs.
+	started    time.Time                // Start of the scenario.
+}
+
+// runScenario is the execution logic for the "scenario run" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: The path of the scenario file.
+//
+// Behavior:
+//   - Loads the scenario and applies its broker override.
+//   - Starts the listener in this process, with a fault injector driven by the inject phases.
+//   - Runs the phases in order: publisher runs, pauses, cron relay processes started, stopped (SIGTERM) or
+//     killed (SIGKILL), listener failure rates, and waits until the committed events were delivered and relayed.
+//   - Stops the cron relay and the listener, prints the listener reports, then checks the expectations.
+//
+// Returns:
+//   - nil if every phase ran and every expectation holds.
+//   - An error if the scenario is invalid, a phase fails or an expectation does not hold.
+func runScenario(cmd *cobra.Command, args []string) error {
+	// Step 1: Load the scenario and select its broker.
+	scenario, err := services.LoadScenario(args[0], appConfig)
+	if err != nil {
+		return err
+	}
+	if scenario.Broker != "" {
+		appConfig.Broker.Type = scenario.Broker
+		if err := appConfig.Validate(); err != nil {
+			return fmt.Errorf("invalid broker of scenario %s: %w", scenario.Name, err)
+		}
+	}
+	if scenario.Ordered {
+		appConfig.PubSub.MessageOrdering = true
+	}
+	if scenario.UsesCron() && appConfig.Broker.Type == config.BrokerGoChannel {
+		return fmt.Errorf("the %s broker does not cross processes, so the cron relay cannot reach the listener; use the %s broker instead", config.BrokerGoChannel, config.BrokerSQL)
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := &scenarioRunner{
+		scenario: scenario,
+		opts: services.ListenerOptions{
+			Tracker: services.NewDeliveryTracker(0),
+			Latency: services.NewLatencyRecorder(),
+			Faults:  &services.FaultInjector{},
+		},
+		started: time.Now(),
+	}
+	if scenario.Ordered {
+		runner.opts.Ordering = services.NewOrderValidator()
+	}
+	idempotency, err := services.NewListenerIdempotency(appConfig)
+	if err != nil {
+		return err
+	}
+	runner.opts.Idempotency = idempotency
+
+	// Step 2: Hand the resolved configuration to the cron relay processes.
+	if scenario.UsesCron() {
+		f, err := os.CreateTemp("", "outbox-scenario-*.yaml")
+		if err != nil {
+			return fmt.Errorf("create cron config file: %w", err)
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		if err := appConfig.WriteFile(f.Name()); err != nil {
+			return err
+		}
+		runner.configPath = f.Name()
+	}
+
+	// Step 3: Start the listener and wait until it is subscribed.
+	logger := watermill.NewStdLogger(false, false)
+	router := newListenerRouter(logger)
+	services.SubOutboxDebugger(appConfig, router, logger, runner.opts)
+
+	routerErr := make(chan error, 1)
+	go func() {
+		routerErr <- router.Run(ctx)
+	}()
+	select {
+	case <-router.Running():
+	case err := <-routerErr:
+		return fmt.Errorf("listener stopped: %w", err)
+	}
+
+	// Step 4: Run the phases in order, stopping at the first failure.
+	fmt.Printf("Running scenario %q (%d phases, broker %s)\n", scenario.Name, len(scenario.Phases), appConfig.Broker.Type)
+	phaseErr := runner.runPhases(ctx)
+
+	// Step 5: Stop the cron relay and the listener, then close the idempotency store.
+	runner.stopCron(syscall.SIGTERM)
+	router.Close()
+	if err := <-routerErr; err != nil {
+		log.Error().Msgf("Recover Event Message With Error: %v", err)
+	}
+	closeListenerIdempotency(idempotency)
+
+	// Step 6: Print the listener reports and check the expectations on the runs of the scenario.
+	report := runner.opts.Tracker.Report().ForRuns(runner.runIDs)
+	report.Expected = runner.committed
+	fmt.Println()
+	printListenerReports(os.Stdout, runner.opts)
+	var ordering *services.OrderingReport
+	if runner.opts.Ordering != nil {
+		filtered := runner.opts.Ordering.Report().ForRuns(runner.runIDs)
+		ordering = &filtered
+	}
+	failures, err := scenario.Expect.Evaluate(context.Background(), appConfig, report, ordering, runner.started)
+	if err != nil {
+		return err
+	}
+	if phaseErr != nil {
+		failures = append([]string{phaseErr.Error()}, failures...)
+	}
+
+	// Step 7: Print the verdict.
+	fmt.Println()
+	if len(failures) > 0 {
+		fmt.Printf("Scenario %q: FAIL\n", scenario.Name)
+		for _, failure := range failures {
+			fmt.Printf("  - %s\n", failure)
+		}
+		return fmt.Errorf("scenario %q failed: %d expectation(s) not met", scenario.Name, len(failures))
+	}
+	fmt.Printf("Scenario %q: PASS\n", scenario.Name)
+
+	return nil
+}
+
+// runPhases runs the phases of the scenario in order.
+//
+// Returns:
+//   - nil once every phase ran.
+//   - An error naming the phase that failed or was interrupted.
+func (r *scenarioRunner) runPhases(ctx context.Context) error {
+	for i, phase := range r.scenario.Phases {
+		name := fmt.Sprintf("phase %d (%s)", i+1, phase.Name)
+		log.Info().Msgf("[Scenario] Starting %s", name)
+
+		var err error
+		switch {
+		case phase.Publish != nil:
+			result := services.PubOutboxDebugger(ctx, appConfig, phase.Publish.Options())
+			printPublishResult(os.Stdout, result)
+			r.runIDs = append(r.runIDs, result.RunID)
+			r.committed += result.Events
+		case phase.Pause > 0:
+			err = sleepContext(ctx, phase.Pause)
+		case phase.Cron == services.CronStart:
+			err = r.startCron()
+		case phase.Cron == services.CronStop:
+			r.stopCron(syscall.SIGTERM)
+		case phase.Cron == services.CronKill:
+			r.stopCron(syscall.SIGKILL)
+		case phase.Inject != nil:
+			r.opts.Faults.SetFailureRate(phase.Inject.ListenerFailureRate)
+			log.Info().Msgf("[Scenario] Listener failure rate set to %.2f", phase.Inject.ListenerFailureRate)
+		case phase.WaitDrain != nil:
+			err = r.waitDrain(ctx, phase.WaitDrain)
+		}
+
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// startCron starts a cron relay process running this executable with the resolved configuration.
+// The OUTBOX_ environment variables are not passed on, since the configuration file already resolves them.
+func (r *scenarioRunner) startCron() error {
+	if r.cron != nil {
+		return errors.New("the cron relay is already running")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+
+	args := append([]string{"cron", "--" + config.FlagConfigFile, r.configPath}, r.scenario.CronArgs...)
+	cron := exec.Command(executable, args...)
+	cron.Stdout = os.Stdout
+	cron.Stderr = os.Stderr
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "OUTBOX_") {
+			cron.Env = append(cron.Env, env)
+		}
+	}
+	if err := cron.Start(); err != nil {
+		return fmt.Errorf("start cron relay: %w", err)
+	}
+
+	log.Info().Msgf("[Scenario] Cron relay started (pid %d)", cron.Process.Pid)
+	r.cron = cron
+	return nil
+}
+
+// stopCron sends the signal to the cron relay process and waits for it to exit; it does nothing if none is running.
+func (r *scenarioRunner) stopCron(sig syscall.Signal) {
+	if r.cron == nil {
+		return
+	}
+
+	if err := r.cron.Process.Signal(sig); err != nil {
+		log.Error().Msgf("Signal cron relay: %v", err)
+	}
+	err := r.cron.Wait()
+	log.Info().Msgf("[Scenario] Cron relay (pid %d) stopped with %v: %v", r.cron.Process.Pid, sig, err)
+	r.cron = nil
+}
+
+// waitDrain waits until every event committed so far was delivered and no outbox row created during the scenario
+// is left with a pending status.
+//
+// Returns:
+//   - nil once drained.
+//   - An error if the timeout expires, the context is cancelled or the outbox cannot be queried.
+func (r *scenarioRunner) waitDrain(ctx context.Context, phase *services.WaitDrainPhase) error {
+	deadline := time.Now().Add(phase.Timeout)
+	for {
+		// Step 1: Count the delivered events and the pending rows.
+		delivered := r.opts.Tracker.Report().ForRuns(r.runIDs).Unique
+		pending, err := services.CountOutbox(ctx, appConfig, services.OutboxFilter{
+			Statuses:    phase.PendingStatuses,
+			CreatedFrom: r.started,
+		})
+		if err != nil {
+			return err
+		}
+		if delivered >= r.committed && pending == 0 {
+			log.Info().Msgf("[Scenario] Drained: %d of %d events delivered, no pending outbox row", delivered, r.committed)
+			return nil
+		}
+
+		// Step 2: Wait for the next check.
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not drained after %v: %d of %d events delivered, %d pending outbox row(s)", phase.Timeout, delivered, r.committed, pending)
+		}
+		log.Info().Msgf("[Scenario] Waiting for the drain: %d of %d events delivered, %d pending outbox row(s)", delivered, r.committed, pending)
+		if err := sleepContext(ctx, phase.Interval); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext waits for the duration or until the context is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,138 @@
+// Package cmd provides command-line interface (CLI) commands for the Outbox Debugger application.
+// This file defines the "stats" command for summarizing the health of the outbox tables.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"outbox/debugger/services"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags for the "stats" command
+	statsTables   []int         // Table indexes to aggregate.
+	statsWatch    bool          // Refresh the summary periodically.
+	statsInterval time.Duration // Refresh interval in watch mode.
+)
+
+var (
+	// statsCmd defines the "stats" command for summarizing the outbox tables.
+	statsCmd = &cobra.Command{
+		Use:   "stats",                                                               // Command usage text.
+		Short: "Outbox health summary",                                               // Brief description of the command.
+		Long:  "Aggregate the event_outbox tables by table index, topic and status.", // Detailed description of the command.
+		RunE:  runStats,                                                              // Function to execute when the command is run.
+	}
+)
+
+// StatsCmd returns the "stats" command to be registered with the root command.
+//
+// Behavior:
+//   - Defines flags for the aggregated tables and the watch mode.
+//   - Executes the runStats function when invoked.
+func StatsCmd() *cobra.Command {
+	statsCmd.Flags().IntSliceVar(&statsTables, "table", nil, "Outbox table indexes to aggregate (default: all tables)")
+	statsCmd.Flags().BoolVar(&statsWatch, "watch", false, "Refresh the summary periodically until interrupted")
+	statsCmd.Flags().DurationVar(&statsInterval, "interval", 5*time.Second, "Refresh interval in watch mode")
+	return statsCmd
+}
+
+// runStats is the execution logic for the "stats" command.
+//
+// Parameters:
+//   - cmd: The command instance triggering this function.
+//   - args: Command-line arguments passed to the command.
+//
+// Behavior:
+//   - Prints a single summary, or refreshes it every interval in watch mode until SIGINT/SIGTERM.
+//   - In watch mode, prints the change of the row counts since the previous refresh.
+//
+// Returns:
+//   - nil once the summary was printed or the watch was interrupted.
+//   - An error object if the tables are invalid or a query fails.
+func runStats(cmd *cobra.Command, args []string) error {
+	// Step 1: Stop watching on SIGINT/SIGTERM.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	interval := time.Duration(0)
+	if statsWatch {
+		interval = statsInterval
+	}
+
+	// Step 2: Print every snapshot, clearing the screen between refreshes.
+	var previous *services.OutboxStats
+	return services.WatchOutboxStats(ctx, appConfig, statsTables, interval, func(stats services.OutboxStats) {
+		if statsWatch {
+			fmt.Print("\033[H\033[2J")
+		}
+		printStats(os.Stdout, stats, previous)
+		previous = &stats
+	})
+}
+
+// printStats prints a statistics snapshot.
+//
+// Parameters:
+//   - w: The writer receiving the output.
+//   - stats: The snapshot to print.
+//   - previous: The previous snapshot in watch mode, or nil.
+func printStats(w io.Writer, stats services.OutboxStats, previous *services.OutboxStats) {
+	// Step 1: Print the totals and, in watch mode, their change.
+	fmt.Fprintf(w, "Outbox stats at %s\n", stats.CollectedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "  Total rows: %d\n", stats.Total)
+	fmt.Fprintf(w, "  Overdue rows: %d\n", stats.Overdue)
+	if previous != nil {
+		elapsed := stats.CollectedAt.Sub(previous.CollectedAt).Seconds()
+		delta := stats.Total - previous.Total
+		fmt.Fprintf(w, "  Change since last refresh: %+d rows (%.1f rows/s)\n", delta, float64(delta)/elapsed)
+
+		totals, previousTotals := stats.StatusTotals(), previous.StatusTotals()
+		statuses := make([]string, 0, len(totals))
+		for status := range totals {
+			statuses = append(statuses, status)
+		}
+		for status := range previousTotals {
+			if _, ok := totals[status]; !ok {
+				statuses = append(statuses, status)
+			}
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(w, "    %s: %d (%+d)\n", status, totals[status], totals[status]-previousTotals[status])
+		}
+	}
+
+	// Step 2: Print the aggregates per table, topic and status.
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tTOPIC\tSTATUS\tCOUNT\tOVERDUE\tMAX RETRY\tOLDEST AGE")
+	for _, group := range stats.Groups {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			group.TableIndex, group.Topic, group.Status, group.Count, group.Overdue, group.MaxRetryCount,
+			group.OldestAge.Truncate(time.Second))
+	}
+	tw.Flush()
+
+	// Step 3: Print the retry count histogram.
+	fmt.Fprintln(w, "\nRetry count histogram")
+	var largest int64
+	for _, bucket := range stats.RetryHistogram {
+		largest = max(largest, bucket.Count)
+	}
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, bucket := range stats.RetryHistogram {
+		bar := strings.Repeat("#", int(max(1, bucket.Count*40/largest)))
+		fmt.Fprintf(tw, "  %d\t%d\t%s\n", bucket.RetryCount, bucket.Count, bar)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,519 @@
+// Package config provides the layered runtime configuration for the Outbox Debugger application.
+// This file defines the configuration model, its built-in defaults and its validation rules.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"outbox/debugger/enum"
+	"strings"
+)
+
+// Config is the complete runtime configuration of the Outbox Debugger.
+//
+// Values are resolved in layers, each one overriding the previous:
+//   - Built-in defaults taken from the `enum` package.
+//   - A YAML or TOML configuration file.
+//   - Environment variables prefixed with `OUTBOX_`.
+//   - Persistent flags registered on the root command.
+type Config struct {
+	Database DatabaseConfig `yaml:"database" toml:"database"` // Database connection settings.
+	PubSub   PubSubConfig   `yaml:"pubsub" toml:"pubsub"`     // Topic and subscription settings shared by every broker.
+	Broker   BrokerConfig   `yaml:"broker" toml:"broker"`     // Message broker selection and settings.
+	Outbox   OutboxConfig   `yaml:"outbox" toml:"outbox"`     // Outbox table settings.
+	Relay    RelayConfig    `yaml:"relay" toml:"relay"`       // Outbox manager and cron relay settings.
+	Listener ListenerConfig `yaml:"listener" toml:"listener"` // Listener settings.
+}
+
+// DatabaseConfig holds the connection settings of the outbox database.
+type DatabaseConfig struct {
+	DSN                   string `yaml:"dsn" toml:"dsn"`                                     // Database connection string.
+	MaxOpenConnections    int    `yaml:"maxOpenConnections" toml:"maxOpenConnections"`       // Maximum number of open connections to the database.
+	MaxIdleConnections    int    `yaml:"maxIdleConnections" toml:"maxIdleConnections"`       // Maximum number of idle connections in the pool.
+	ConnectionMaxLifetime int    `yaml:"connectionMaxLifetime" toml:"connectionMaxLifetime"` // Maximum lifetime (in seconds) of a connection.
+	Retry                 int    `yaml:"retry" toml:"retry"`                                 // Number of retry attempts for database operations.
+}
+
+// PubSubConfig holds the topic and subscription settings.
+// The subscriber name is also used as consumer group by the brokers that have one.
+type PubSubConfig struct {
+	ProjectID       string `yaml:"projectId" toml:"projectId"`             // Google Cloud Project ID.
+	SubscriberName  string `yaml:"subscriberName" toml:"subscriberName"`   // Name of the Pub/Sub subscriber.
+	TopicName       string `yaml:"topicName" toml:"topicName"`             // Name of the Pub/Sub topic.
+	MessageOrdering bool   `yaml:"messageOrdering" toml:"messageOrdering"` // Subscribe with per-ordering-key delivery order.
+}
+
+// Supported message brokers.
+const (
+	BrokerGCP       = "gcp"       // Google Cloud Pub/Sub.
+	BrokerKafka     = "kafka"     // Apache Kafka.
+	BrokerNATS      = "nats"      // NATS (core or JetStream).
+	BrokerAMQP      = "amqp"      // AMQP 0.9.1 (RabbitMQ).
+	BrokerRedis     = "redis"     // Redis Streams.
+	BrokerGoChannel = "gochannel" // In-process Go channels.
+	BrokerSQL       = "sql"       // PostgreSQL tables of the outbox database.
+)
+
+// BrokerConfig selects the message broker and holds its connection settings.
+type BrokerConfig struct {
+	Type  string      `yaml:"type" toml:"type"`   // Broker used by the publisher, the relay and the listener.
+	Kafka KafkaConfig `yaml:"kafka" toml:"kafka"` // Kafka settings.
+	NATS  NATSConfig  `yaml:"nats" toml:"nats"`   // NATS settings.
+	AMQP  AMQPConfig  `yaml:"amqp" toml:"amqp"`   // AMQP settings.
+	Redis RedisConfig `yaml:"redis" toml:"redis"` // Redis settings.
+}
+
+// KafkaConfig holds the Kafka connection settings.
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers" toml:"brokers"` // Kafka bootstrap broker addresses.
+}
+
+// NATSConfig holds the NATS connection settings.
+type NATSConfig struct {
+	URL       string `yaml:"url" toml:"url"`             // NATS server URL.
+	JetStream bool   `yaml:"jetStream" toml:"jetStream"` // Use JetStream instead of core NATS.
+}
+
+// AMQPConfig holds the AMQP connection settings.
+type AMQPConfig struct {
+	URI string `yaml:"uri" toml:"uri"` // AMQP connection URI.
+}
+
+// RedisConfig holds the Redis connection settings.
+type RedisConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"`         // Redis server address.
+	Password string `yaml:"password" toml:"password"` // Redis password.
+	DB       int    `yaml:"db" toml:"db"`             // Redis database number.
+}
+
+// OutboxConfig holds the settings of the outbox tables.
+// When Topics is empty, pubsub.topicName is the only topic, stored in the table of TableIndex.
+type OutboxConfig struct {
+	TableIndex          int                 `yaml:"tableIndex" toml:"tableIndex"`                   // Index of the table used for the outbox pattern.
+	DeleteExistingOnAdd bool                `yaml:"deleteExistingOnAdd" toml:"deleteExistingOnAdd"` // Whether to delete existing entries when adding new ones.
+	Topics              []OutboxTopicConfig `yaml:"topics" toml:"topics"`                           // Topics of a sharded outbox, each one mapped to its table.
+}
+
+// OutboxTopicConfig maps a topic to the outbox table storing its events.
+type OutboxTopicConfig struct {
+	Name                string `yaml:"name" toml:"name"`                               // Name of the topic.
+	TableIndex          int    `yaml:"tableIndex" toml:"tableIndex"`                   // Index of the table storing the events of the topic.
+	DeleteExistingOnAdd bool   `yaml:"deleteExistingOnAdd" toml:"deleteExistingOnAdd"` // Whether to delete existing entries of the topic when adding new ones.
+}
+
+// OutboxTopics returns the topics registered in the outbox.
+//
+// Returns:
+//   - outbox.topics if set.
+//   - Otherwise a single topic built from pubsub.topicName, outbox.tableIndex and outbox.deleteExistingOnAdd.
+func (c *Config) OutboxTopics() []OutboxTopicConfig {
+	if len(c.Outbox.Topics) > 0 {
+		return c.Outbox.Topics
+	}
+
+	return []OutboxTopicConfig{{
+		Name:                c.PubSub.TopicName,
+		TableIndex:          c.Outbox.TableIndex,
+		DeleteExistingOnAdd: c.Outbox.DeleteExistingOnAdd,
+	}}
+}
+
+// OutboxTopicNames returns the names of the topics registered in the outbox, in configuration order.
+func (c *Config) OutboxTopicNames() []string {
+	topics := c.OutboxTopics()
+	names := make([]string, len(topics))
+	for i, topic := range topics {
+		names[i] = topic.Name
+	}
+	return names
+}
+
+// RelayConfig holds the settings of the EventOutboxManager and of its cron relay.
+type RelayConfig struct {
+	BatchSize     int  `yaml:"batchSize" toml:"batchSize"`         // Maximum number of outbox rows relayed per cron run.
+	Interval      int  `yaml:"interval" toml:"interval"`           // Interval (in seconds) between two cron runs.
+	TableCount    int  `yaml:"tableCount" toml:"tableCount"`       // Number of event_outbox tables handled by the manager.
+	ManagerOption bool `yaml:"managerOption" toml:"managerOption"` // Last boolean option of NewEventOutboxManager, passed through as is.
+	MaxRetries    int  `yaml:"maxRetries" toml:"maxRetries"`       // Retries after which a row is marked EXHAUSTED, 0 for no limit.
+	SweepInterval int  `yaml:"sweepInterval" toml:"sweepInterval"` // Interval (in seconds) between two searches for exhausted rows.
+}
+
+// Dead-letter targets of the listener.
+const (
+	DeadLetterNone  = "none"  // Poison messages are nacked like retryable failures.
+	DeadLetterTopic = "topic" // Poison messages are published to listener.deadLetter.topic on the configured broker.
+	DeadLetterTable = "table" // Poison messages are stored in the listener_dead_letter table of the outbox database.
+)
+
+// ListenerConfig holds the settings of the listener.
+type ListenerConfig struct {
+	DeadLetter  DeadLetterConfig          `yaml:"deadLetter" toml:"deadLetter"`   // Destination of the poison messages.
+	Middleware  ListenerMiddlewareConfig  `yaml:"middleware" toml:"middleware"`   // Retry, timeout and throttle settings of every handler.
+	Handlers    []ListenerHandlerOverride `yaml:"handlers" toml:"handlers"`       // Settings overriding listener.middleware for the handler of a topic.
+	Idempotency IdempotencyConfig         `yaml:"idempotency" toml:"idempotency"` // Detection of the messages already processed.
+	ErrorRules  []string                  `yaml:"errorRules" toml:"errorRules"`   // Rules classifying the handler errors, "class:match:value", tried before the built-in rules.
+}
+
+// Error classes of the listener error rules, see helper.ErrorClass.
+const (
+	ErrorClassRetryable = "retryable" // The message is redelivered.
+	ErrorClassPermanent = "permanent" // The message is acked and dropped.
+	ErrorClassPoison    = "poison"    // The message is forwarded to the dead-letter target.
+	ErrorClassDuplicate = "duplicate" // The message was already processed and is acked.
+)
+
+// Matchers of the listener error rules.
+const (
+	ErrorMatchMessage      = "message"      // The error message contains the value; the only string matching, used only when configured.
+	ErrorMatchPostgresCode = "postgresCode" // The error chain holds a lib/pq error with the value as code.
+)
+
+// ErrorRule is a parsed listener error rule.
+type ErrorRule struct {
+	Class string // Class of the matched errors.
+	Match string // Matcher of the rule: message or postgresCode.
+	Value string // Text or code matched.
+}
+
+// ParseErrorRule parses a listener error rule of the form "class:match:value",
+// e.g. "permanent:message:tag validation failed" or "duplicate:postgresCode:23505".
+//
+// Returns:
+//   - The parsed rule.
+//   - An error if the class or the matcher is unknown or the value is empty.
+func ParseErrorRule(raw string) (ErrorRule, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return ErrorRule{}, fmt.Errorf("%q is not of the form class:match:value", raw)
+	}
+	rule := ErrorRule{Class: parts[0], Match: parts[1], Value: parts[2]}
+
+	switch rule.Class {
+	case ErrorClassRetryable, ErrorClassPermanent, ErrorClassPoison, ErrorClassDuplicate:
+	default:
+		return ErrorRule{}, fmt.Errorf("class %q of %q is not one of %s, %s, %s, %s", rule.Class, raw,
+			ErrorClassRetryable, ErrorClassPermanent, ErrorClassPoison, ErrorClassDuplicate)
+	}
+	if rule.Match != ErrorMatchMessage && rule.Match != ErrorMatchPostgresCode {
+		return ErrorRule{}, fmt.Errorf("matcher %q of %q is not one of %s, %s", rule.Match, raw, ErrorMatchMessage, ErrorMatchPostgresCode)
+	}
+	return rule, nil
+}
+
+// Idempotency stores of the listener.
+const (
+	IdempotencyNone     = "none"     // Every delivered message is processed.
+	IdempotencyMemory   = "memory"   // Processed keys are kept in an in-memory LRU of listener.idempotency.capacity keys.
+	IdempotencyPostgres = "postgres" // Processed keys are stored in the listener_processed_message table of the outbox database.
+)
+
+// Idempotency keys of the listener.
+const (
+	IdempotencyKeyUUID    = "uuid"    // The UUID of the message.
+	IdempotencyKeyPayload = "payload" // The SHA-256 of the message payload.
+)
+
+// IdempotencyConfig selects how the listener detects the messages it already processed.
+type IdempotencyConfig struct {
+	Store    string `yaml:"store" toml:"store"`       // Store of the processed keys: none, memory or postgres.
+	Key      string `yaml:"key" toml:"key"`           // Key of a message: uuid or payload.
+	Capacity int    `yaml:"capacity" toml:"capacity"` // Maximum number of keys kept by the memory store.
+}
+
+// ListenerMiddlewareConfig holds the retry, timeout and throttle settings of a listener handler.
+type ListenerMiddlewareConfig struct {
+	Retry    RetryConfig `yaml:"retry" toml:"retry"`       // In-process retries of the failed messages.
+	Timeout  int         `yaml:"timeout" toml:"timeout"`   // Deadline (in milliseconds) of every processing attempt, 0 for none.
+	Throttle int         `yaml:"throttle" toml:"throttle"` // Maximum number of messages handled per second, 0 for no limit.
+}
+
+// RetryConfig holds the exponential backoff of the in-process retries.
+// A message still failing after MaxRetries retries is nacked, so the broker redelivers it.
+type RetryConfig struct {
+	MaxRetries      int     `yaml:"maxRetries" toml:"maxRetries"`           // Retries of a failed message, 0 disables the retries.
+	InitialInterval int     `yaml:"initialInterval" toml:"initialInterval"` // Delay (in milliseconds) before the first retry.
+	MaxInterval     int     `yaml:"maxInterval" toml:"maxInterval"`         // Maximum delay (in milliseconds) between two retries.
+	Multiplier      float64 `yaml:"multiplier" toml:"multiplier"`           // Factor applied to the delay after every retry.
+	Jitter          float64 `yaml:"jitter" toml:"jitter"`                   // Fraction of the delay randomly added or removed, between 0 and 1.
+}
+
+// ListenerHandlerOverride overrides listener.middleware for the handler of one topic.
+// Unset keys keep the value of listener.middleware; a retry block replaces listener.middleware.retry as a whole.
+type ListenerHandlerOverride struct {
+	Topic    string       `yaml:"topic" toml:"topic"`       // Topic of the handler.
+	Retry    *RetryConfig `yaml:"retry" toml:"retry"`       // Retry settings of the handler.
+	Timeout  *int         `yaml:"timeout" toml:"timeout"`   // Deadline (in milliseconds) of every processing attempt of the handler.
+	Throttle *int         `yaml:"throttle" toml:"throttle"` // Maximum number of messages handled per second by the handler.
+}
+
+// HandlerMiddleware returns the middleware settings of the handler of a topic.
+//
+// Returns:
+//   - listener.middleware with the keys set by the listener.handlers entry of the topic, if any, applied on top.
+func (c *Config) HandlerMiddleware(topic string) ListenerMiddlewareConfig {
+	settings := c.Listener.Middleware
+	for _, override := range c.Listener.Handlers {
+		if override.Topic == topic {
+			settings = settings.with(override)
+		}
+	}
+	return settings
+}
+
+// with returns the middleware settings with the keys set by the override applied on top.
+func (m ListenerMiddlewareConfig) with(override ListenerHandlerOverride) ListenerMiddlewareConfig {
+	if override.Retry != nil {
+		m.Retry = *override.Retry
+	}
+	if override.Timeout != nil {
+		m.Timeout = *override.Timeout
+	}
+	if override.Throttle != nil {
+		m.Throttle = *override.Throttle
+	}
+	return m
+}
+
+// validate checks the middleware settings, prefixing every error with the configuration path.
+func (m ListenerMiddlewareConfig) validate(path string) []error {
+	var errs []error
+	if m.Retry.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("%s.retry.maxRetries must not be negative", path))
+	}
+	if m.Retry.MaxRetries > 0 {
+		if m.Retry.InitialInterval <= 0 {
+			errs = append(errs, fmt.Errorf("%s.retry.initialInterval must be greater than 0", path))
+		}
+		if m.Retry.MaxInterval < m.Retry.InitialInterval {
+			errs = append(errs, fmt.Errorf("%s.retry.maxInterval must not be less than %s.retry.initialInterval", path, path))
+		}
+		if m.Retry.Multiplier < 1 {
+			errs = append(errs, fmt.Errorf("%s.retry.multiplier must be at least 1", path))
+		}
+		if m.Retry.Jitter < 0 || m.Retry.Jitter > 1 {
+			errs = append(errs, fmt.Errorf("%s.retry.jitter must be between 0 and 1", path))
+		}
+	}
+	if m.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("%s.timeout must not be negative", path))
+	}
+	if m.Throttle < 0 {
+		errs = append(errs, fmt.Errorf("%s.throttle must not be negative", path))
+	}
+	return errs
+}
+
+// DeadLetterConfig selects where the listener forwards the messages it can never process.
+type DeadLetterConfig struct {
+	Target string `yaml:"target" toml:"target"` // Dead-letter target: none, topic or table.
+	Topic  string `yaml:"topic" toml:"topic"`   // Topic receiving the poison messages with the topic target.
+}
+
+// Default returns the configuration built from the constants of the `enum` package.
+//
+// Returns:
+//   - A new Config populated with the built-in defaults.
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			DSN:                   enum.DbDSN,
+			MaxOpenConnections:    enum.DbMaxOpenConnections,
+			MaxIdleConnections:    enum.DbMaxIdleConnections,
+			ConnectionMaxLifetime: enum.DbConnectionMaxLifetime,
+			Retry:                 enum.DbRetry,
+		},
+		PubSub: PubSubConfig{
+			ProjectID:      enum.ProjectId,
+			SubscriberName: enum.SubscriberName,
+			TopicName:      enum.TopicName,
+		},
+		Broker: BrokerConfig{
+			Type:  enum.BrokerType,
+			Kafka: KafkaConfig{Brokers: []string{enum.KafkaBroker}},
+			NATS:  NATSConfig{URL: enum.NatsURL},
+			AMQP:  AMQPConfig{URI: enum.AmqpURI},
+			Redis: RedisConfig{Addr: enum.RedisAddr},
+		},
+		Outbox: OutboxConfig{
+			TableIndex:          enum.TableIndex,
+			DeleteExistingOnAdd: enum.DeleteExistingOnAdd,
+		},
+		Relay: RelayConfig{
+			BatchSize:     enum.RelayBatchSize,
+			Interval:      enum.RelayInterval,
+			TableCount:    enum.TableCount,
+			ManagerOption: enum.RelayManagerOption,
+			MaxRetries:    enum.RelayMaxRetries,
+			SweepInterval: enum.RelaySweepInterval,
+		},
+		Listener: ListenerConfig{
+			DeadLetter: DeadLetterConfig{
+				Target: enum.DeadLetterTarget,
+				Topic:  enum.DeadLetterTopic,
+			},
+			Middleware: ListenerMiddlewareConfig{
+				Retry: RetryConfig{
+					MaxRetries:      enum.ListenerMaxRetries,
+					InitialInterval: enum.ListenerRetryInitialInterval,
+					MaxInterval:     enum.ListenerRetryMaxInterval,
+					Multiplier:      enum.ListenerRetryMultiplier,
+					Jitter:          enum.ListenerRetryJitter,
+				},
+				Timeout:  enum.ListenerTimeout,
+				Throttle: enum.ListenerThrottle,
+			},
+			Idempotency: IdempotencyConfig{
+				Store:    enum.IdempotencyStore,
+				Key:      enum.IdempotencyKey,
+				Capacity: enum.IdempotencyCapacity,
+			},
+		},
+	}
+}
+
+// Validate checks that the configuration is usable.
+//
+// Returns:
+//   - nil if every setting is valid.
+//   - An error joining every invalid setting otherwise.
+func (c *Config) Validate() error {
+	var errs []error
+
+	// Step 1: Validate the database settings.
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn must not be empty"))
+	}
+	if c.Database.MaxOpenConnections <= 0 {
+		errs = append(errs, errors.New("database.maxOpenConnections must be greater than 0"))
+	}
+	if c.Database.MaxIdleConnections < 0 || c.Database.MaxIdleConnections > c.Database.MaxOpenConnections {
+		errs = append(errs, errors.New("database.maxIdleConnections must be between 0 and database.maxOpenConnections"))
+	}
+	if c.Database.ConnectionMaxLifetime < 0 {
+		errs = append(errs, errors.New("database.connectionMaxLifetime must not be negative"))
+	}
+	if c.Database.Retry < 0 {
+		errs = append(errs, errors.New("database.retry must not be negative"))
+	}
+
+	// Step 2: Validate the Pub/Sub settings.
+	if c.Broker.Type == BrokerGCP && c.PubSub.ProjectID == "" {
+		errs = append(errs, errors.New("pubsub.projectId must not be empty"))
+	}
+	if c.PubSub.SubscriberName == "" {
+		errs = append(errs, errors.New("pubsub.subscriberName must not be empty"))
+	}
+	if c.PubSub.TopicName == "" {
+		errs = append(errs, errors.New("pubsub.topicName must not be empty"))
+	}
+
+	// Step 3: Validate the broker settings.
+	switch c.Broker.Type {
+	case BrokerGCP, BrokerGoChannel, BrokerSQL:
+	case BrokerKafka:
+		if len(c.Broker.Kafka.Brokers) == 0 {
+			errs = append(errs, errors.New("broker.kafka.brokers must not be empty"))
+		}
+	case BrokerNATS:
+		if c.Broker.NATS.URL == "" {
+			errs = append(errs, errors.New("broker.nats.url must not be empty"))
+		}
+	case BrokerAMQP:
+		if c.Broker.AMQP.URI == "" {
+			errs = append(errs, errors.New("broker.amqp.uri must not be empty"))
+		}
+	case BrokerRedis:
+		if c.Broker.Redis.Addr == "" {
+			errs = append(errs, errors.New("broker.redis.addr must not be empty"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("broker.type %q is not one of %s, %s, %s, %s, %s, %s, %s", c.Broker.Type,
+			BrokerGCP, BrokerKafka, BrokerNATS, BrokerAMQP, BrokerRedis, BrokerGoChannel, BrokerSQL))
+	}
+
+	// Step 4: Validate the outbox settings against the tables handled by the relay.
+	if c.Relay.TableCount < 1 || c.Relay.TableCount > enum.TableCount {
+		errs = append(errs, fmt.Errorf("relay.tableCount must be between 1 and %d", enum.TableCount))
+	}
+	tableCount := min(max(c.Relay.TableCount, 1), enum.TableCount)
+	if c.Outbox.TableIndex < 1 || c.Outbox.TableIndex > tableCount {
+		errs = append(errs, fmt.Errorf("outbox.tableIndex must be between 1 and %d", tableCount))
+	}
+	seen := make(map[string]bool, len(c.Outbox.Topics))
+	for i, topic := range c.Outbox.Topics {
+		switch {
+		case topic.Name == "":
+			errs = append(errs, fmt.Errorf("outbox.topics[%d].name must not be empty", i))
+		case seen[topic.Name]:
+			errs = append(errs, fmt.Errorf("outbox.topics[%d].name %q is already configured", i, topic.Name))
+		}
+		seen[topic.Name] = true
+		if topic.TableIndex < 1 || topic.TableIndex > tableCount {
+			errs = append(errs, fmt.Errorf("outbox.topics[%d].tableIndex must be between 1 and %d", i, tableCount))
+		}
+	}
+
+	// Step 5: Validate the relay settings.
+	if c.Relay.BatchSize <= 0 {
+		errs = append(errs, errors.New("relay.batchSize must be greater than 0"))
+	}
+	if c.Relay.Interval <= 0 {
+		errs = append(errs, errors.New("relay.interval must be greater than 0"))
+	}
+	if c.Relay.MaxRetries < 0 {
+		errs = append(errs, errors.New("relay.maxRetries must not be negative"))
+	}
+	if c.Relay.MaxRetries > 0 && c.Relay.SweepInterval <= 0 {
+		errs = append(errs, errors.New("relay.sweepInterval must be greater than 0 when relay.maxRetries is set"))
+	}
+
+	// Step 6: Validate the listener settings.
+	switch c.Listener.DeadLetter.Target {
+	case DeadLetterNone, DeadLetterTable:
+	case DeadLetterTopic:
+		if c.Listener.DeadLetter.Topic == "" {
+			errs = append(errs, errors.New("listener.deadLetter.topic must not be empty with the topic target"))
+		}
+		for _, topic := range c.OutboxTopicNames() {
+			if topic == c.Listener.DeadLetter.Topic {
+				errs = append(errs, fmt.Errorf("listener.deadLetter.topic %q must not be an outbox topic", topic))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("listener.deadLetter.target %q is not one of %s, %s, %s", c.Listener.DeadLetter.Target,
+			DeadLetterNone, DeadLetterTopic, DeadLetterTable))
+	}
+	errs = append(errs, c.Listener.Middleware.validate("listener.middleware")...)
+	handlers := make(map[string]bool, len(c.Listener.Handlers))
+	for i, override := range c.Listener.Handlers {
+		switch {
+		case override.Topic == "":
+			errs = append(errs, fmt.Errorf("listener.handlers[%d].topic must not be empty", i))
+		case handlers[override.Topic]:
+			errs = append(errs, fmt.Errorf("listener.handlers[%d].topic %q is already configured", i, override.Topic))
+		}
+		handlers[override.Topic] = true
+		errs = append(errs, c.Listener.Middleware.with(override).validate(fmt.Sprintf("listener.handlers[%d]", i))...)
+	}
+	switch c.Listener.Idempotency.Store {
+	case IdempotencyNone, IdempotencyPostgres:
+	case IdempotencyMemory:
+		if c.Listener.Idempotency.Capacity <= 0 {
+			errs = append(errs, errors.New("listener.idempotency.capacity must be greater than 0 with the memory store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("listener.idempotency.store %q is not one of %s, %s, %s", c.Listener.Idempotency.Store,
+			IdempotencyNone, IdempotencyMemory, IdempotencyPostgres))
+	}
+	if c.Listener.Idempotency.Key != IdempotencyKeyUUID && c.Listener.Idempotency.Key != IdempotencyKeyPayload {
+		errs = append(errs, fmt.Errorf("listener.idempotency.key %q is not one of %s, %s", c.Listener.Idempotency.Key,
+			IdempotencyKeyUUID, IdempotencyKeyPayload))
+	}
+	for i, raw := range c.Listener.ErrorRules {
+		if _, err := ParseErrorRule(raw); err != nil {
+			errs = append(errs, fmt.Errorf("listener.errorRules[%d]: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
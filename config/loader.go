@@ -0,0 +1,286 @@
+// Package config provides the layered runtime configuration for the Outbox Debugger application.
+// This file loads the configuration from a file, environment variables and command-line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FlagConfigFile = "config"        // Name of the flag pointing to the configuration file.
+	EnvConfigFile  = "OUTBOX_CONFIG" // Name of the environment variable pointing to the configuration file.
+)
+
+// binding ties a configuration value to its environment variable and command-line flag.
+type binding struct {
+	env   string // Environment variable overriding the value.
+	flag  string // Flag overriding the value.
+	usage string // Usage text of the flag.
+	value any    // Pointer to the configuration field.
+}
+
+// bindings lists every configuration value that can be overridden outside the configuration file.
+//
+// Parameters:
+//   - c: The configuration whose fields are bound.
+//
+// Returns:
+//   - The bindings pointing into the fields of c.
+func bindings(c *Config) []binding {
+	return []binding{
+		{"OUTBOX_DB_DSN", "dbDsn", "Database connection string", &c.Database.DSN},
+		{"OUTBOX_DB_MAX_OPEN_CONNECTIONS", "dbMaxOpenConnections", "Maximum number of open database connections", &c.Database.MaxOpenConnections},
+		{"OUTBOX_DB_MAX_IDLE_CONNECTIONS", "dbMaxIdleConnections", "Maximum number of idle database connections", &c.Database.MaxIdleConnections},
+		{"OUTBOX_DB_CONNECTION_MAX_LIFETIME", "dbConnectionMaxLifetime", "Maximum lifetime (in seconds) of a database connection", &c.Database.ConnectionMaxLifetime},
+		{"OUTBOX_DB_RETRY", "dbRetry", "Number of retry attempts for database operations", &c.Database.Retry},
+		{"OUTBOX_PUBSUB_PROJECT_ID", "projectId", "Google Cloud Project ID", &c.PubSub.ProjectID},
+		{"OUTBOX_PUBSUB_SUBSCRIBER_NAME", "subscriberName", "Name of the Pub/Sub subscriber", &c.PubSub.SubscriberName},
+		{"OUTBOX_PUBSUB_TOPIC_NAME", "topicName", "Name of the Pub/Sub topic", &c.PubSub.TopicName},
+		{"OUTBOX_PUBSUB_MESSAGE_ORDERING", "messageOrdering", "Subscribe with per-ordering-key delivery order", &c.PubSub.MessageOrdering},
+		{"OUTBOX_BROKER", "broker", "Message broker: gcp, kafka, nats, amqp, redis, gochannel or sql", &c.Broker.Type},
+		{"OUTBOX_KAFKA_BROKERS", "kafkaBrokers", "Kafka bootstrap broker addresses", &c.Broker.Kafka.Brokers},
+		{"OUTBOX_NATS_URL", "natsUrl", "NATS server URL", &c.Broker.NATS.URL},
+		{"OUTBOX_NATS_JETSTREAM", "natsJetStream", "Use NATS JetStream instead of core NATS", &c.Broker.NATS.JetStream},
+		{"OUTBOX_AMQP_URI", "amqpUri", "AMQP connection URI", &c.Broker.AMQP.URI},
+		{"OUTBOX_REDIS_ADDR", "redisAddr", "Redis server address", &c.Broker.Redis.Addr},
+		{"OUTBOX_REDIS_PASSWORD", "redisPassword", "Redis password", &c.Broker.Redis.Password},
+		{"OUTBOX_REDIS_DB", "redisDb", "Redis database number", &c.Broker.Redis.DB},
+		{"OUTBOX_TABLE_INDEX", "tableIndex", "Index of the outbox table", &c.Outbox.TableIndex},
+		{"OUTBOX_DELETE_EXISTING_ON_ADD", "deleteExistingOnAdd", "Delete existing entries when adding new ones", &c.Outbox.DeleteExistingOnAdd},
+		{"OUTBOX_RELAY_BATCH_SIZE", "relayBatchSize", "Maximum number of outbox rows relayed per cron run", &c.Relay.BatchSize},
+		{"OUTBOX_RELAY_INTERVAL", "relayInterval", "Interval (in seconds) between two cron runs", &c.Relay.Interval},
+		{"OUTBOX_RELAY_TABLE_COUNT", "relayTableCount", "Number of event_outbox tables handled by the outbox manager", &c.Relay.TableCount},
+		{"OUTBOX_RELAY_MANAGER_OPTION", "relayManagerOption", "Last boolean option of the outbox manager, passed through as is", &c.Relay.ManagerOption},
+		{"OUTBOX_RELAY_MAX_RETRIES", "relayMaxRetries", "Retries after which an outbox row is marked EXHAUSTED (0 for no limit)", &c.Relay.MaxRetries},
+		{"OUTBOX_RELAY_SWEEP_INTERVAL", "relaySweepInterval", "Interval (in seconds) between two searches for exhausted outbox rows", &c.Relay.SweepInterval},
+		{"OUTBOX_DEAD_LETTER_TARGET", "deadLetterTarget", "Destination of the poison messages of the listener: none, topic or table", &c.Listener.DeadLetter.Target},
+		{"OUTBOX_DEAD_LETTER_TOPIC", "deadLetterTopic", "Topic receiving the poison messages with the topic dead-letter target", &c.Listener.DeadLetter.Topic},
+		{"OUTBOX_LISTENER_MAX_RETRIES", "listenerMaxRetries", "In-process retries of a failed message before it is nacked (0 disables the retries)", &c.Listener.Middleware.Retry.MaxRetries},
+		{"OUTBOX_LISTENER_RETRY_INITIAL_INTERVAL", "listenerRetryInitialInterval", "Delay (in milliseconds) before the first retry", &c.Listener.Middleware.Retry.InitialInterval},
+		{"OUTBOX_LISTENER_RETRY_MAX_INTERVAL", "listenerRetryMaxInterval", "Maximum delay (in milliseconds) between two retries", &c.Listener.Middleware.Retry.MaxInterval},
+		{"OUTBOX_LISTENER_RETRY_MULTIPLIER", "listenerRetryMultiplier", "Factor applied to the retry delay after every retry", &c.Listener.Middleware.Retry.Multiplier},
+		{"OUTBOX_LISTENER_RETRY_JITTER", "listenerRetryJitter", "Fraction of the retry delay randomly added or removed (0 to 1)", &c.Listener.Middleware.Retry.Jitter},
+		{"OUTBOX_LISTENER_TIMEOUT", "listenerTimeout", "Deadline (in milliseconds) of every processing attempt (0 for none)", &c.Listener.Middleware.Timeout},
+		{"OUTBOX_LISTENER_THROTTLE", "listenerThrottle", "Maximum number of messages handled per second by every handler (0 for no limit)", &c.Listener.Middleware.Throttle},
+		{"OUTBOX_IDEMPOTENCY_STORE", "idempotencyStore", "Store of the message keys processed by the listener: none, memory or postgres", &c.Listener.Idempotency.Store},
+		{"OUTBOX_IDEMPOTENCY_KEY", "idempotencyKey", "Idempotency key of a message: uuid or payload (SHA-256)", &c.Listener.Idempotency.Key},
+		{"OUTBOX_IDEMPOTENCY_CAPACITY", "idempotencyCapacity", "Maximum number of keys kept by the memory idempotency store", &c.Listener.Idempotency.Capacity},
+		{"OUTBOX_LISTENER_ERROR_RULES", "listenerErrorRules", "Rules classifying the handler errors before the built-in rules, class:match:value with match message or postgresCode", &c.Listener.ErrorRules},
+	}
+}
+
+// RegisterFlags registers the configuration flags on the given flag set.
+//
+// Parameters:
+//   - fs: The flag set receiving the flags, usually the persistent flags of the root command.
+//
+// Behavior:
+//   - Registers the configuration file flag.
+//   - Registers one flag per bound configuration value, using the built-in default as flag default.
+func RegisterFlags(fs *pflag.FlagSet) {
+	fs.String(FlagConfigFile, "", "Path to a YAML or TOML configuration file (env "+EnvConfigFile+")")
+
+	for _, b := range bindings(Default()) {
+		usage := fmt.Sprintf("%s (env %s)", b.usage, b.env)
+		switch v := b.value.(type) {
+		case *string:
+			fs.String(b.flag, *v, usage)
+		case *int:
+			fs.Int(b.flag, *v, usage)
+		case *bool:
+			fs.Bool(b.flag, *v, usage)
+		case *float64:
+			fs.Float64(b.flag, *v, usage)
+		case *[]string:
+			fs.StringSlice(b.flag, *v, usage)
+		}
+	}
+}
+
+// Load resolves the configuration from every layer and validates it.
+//
+// Parameters:
+//   - fs: The flag set previously passed to RegisterFlags.
+//
+// Behavior:
+//   - Starts from the built-in defaults.
+//   - Overrides them with the configuration file given by --config or OUTBOX_CONFIG, if any.
+//   - Overrides them with the OUTBOX_* environment variables that are set.
+//   - Overrides them with the flags explicitly set on the command line.
+//
+// Returns:
+//   - The resolved configuration.
+//   - An error if a layer cannot be read or the result is invalid.
+func Load(fs *pflag.FlagSet) (*Config, error) {
+	cfg := Default()
+
+	// Step 1: Apply the configuration file.
+	path := os.Getenv(EnvConfigFile)
+	if fs.Changed(FlagConfigFile) {
+		path, _ = fs.GetString(FlagConfigFile)
+	}
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 2: Apply the environment variables and the flags.
+	for _, b := range bindings(cfg) {
+		if raw, ok := os.LookupEnv(b.env); ok {
+			if err := setValue(b.value, raw); err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %w", b.env, err)
+			}
+		}
+		if fs.Changed(b.flag) {
+			if err := setFlagValue(b.value, fs, b.flag); err != nil {
+				return nil, fmt.Errorf("invalid value for --%s: %w", b.flag, err)
+			}
+		}
+	}
+
+	// Step 3: Validate the resolved configuration.
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// loadFile decodes a YAML or TOML configuration file on top of cfg.
+//
+// Parameters:
+//   - path: The configuration file path; its extension selects the format.
+//   - cfg: The configuration receiving the decoded values.
+//
+// Error Handling:
+//   - Returns an error for unknown extensions, unreadable files and unknown keys.
+func loadFile(path string, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open config file: %w", err)
+		}
+		defer f.Close()
+
+		decoder := yaml.NewDecoder(f)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("decode config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.DecodeFile(path, cfg)
+		if err != nil {
+			return fmt.Errorf("decode config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("decode config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+
+	return nil
+}
+
+// WriteFile encodes the configuration to a YAML or TOML file, so another process can load the same settings.
+//
+// Parameters:
+//   - path: The configuration file path; its extension selects the format.
+//
+// Error Handling:
+//   - Returns an error for unknown extensions and unwritable files.
+func (c *Config) WriteFile(path string) error {
+	// Step 1: Encode the configuration in the format of the extension.
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		encoded, err := yaml.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("encode config file %s: %w", path, err)
+		}
+		data = encoded
+	case ".toml":
+		var buf strings.Builder
+		if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+			return fmt.Errorf("encode config file %s: %w", path, err)
+		}
+		data = []byte(buf.String())
+	default:
+		return fmt.Errorf("unsupported config file extension %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+
+	// Step 2: Write the file, readable by the owner only since it holds credentials.
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+
+	return nil
+}
+
+// setValue parses raw into the configuration field pointed to by value.
+// Lists are comma-separated.
+func setValue(value any, raw string) error {
+	switch v := value.(type) {
+	case *string:
+		*v = raw
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		*v = n
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		*v = b
+	case *float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		*v = f
+	case *[]string:
+		*v = nil
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*v = append(*v, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported configuration type %T", value)
+	}
+	return nil
+}
+
+// setFlagValue copies the value of the named flag into the configuration field pointed to by value.
+func setFlagValue(value any, fs *pflag.FlagSet, name string) (err error) {
+	switch v := value.(type) {
+	case *string:
+		*v, err = fs.GetString(name)
+	case *int:
+		*v, err = fs.GetInt(name)
+	case *bool:
+		*v, err = fs.GetBool(name)
+	case *float64:
+		*v, err = fs.GetFloat64(name)
+	case *[]string:
+		*v, err = fs.GetStringSlice(name)
+	default:
+		err = fmt.Errorf("unsupported configuration type %T", value)
+	}
+	return err
+}
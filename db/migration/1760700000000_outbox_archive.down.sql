@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS public.event_outbox_archive;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.event_outbox_archive (
+    event_outbox_id uuid not null,
+    table_index integer not null,
+    event_group varchar(100) not null,
+    event_topic varchar(100) not null,
+    event_key varchar(100) not null,
+    event_message bytea not null,
+    retry_count integer not null,
+    last_retry_time_utc timestamp not null,
+    next_retry_time_utc timestamp not null,
+    status varchar(15) not null,
+    hash_value1 varchar(75) not null,
+    created_time_utc timestamp not null,
+    updated_time_utc timestamp not null,
+    row_version uuid not null,
+    archived_time_utc timestamp not null,
+    constraint event_outbox_archive_pk primary key (table_index, event_outbox_id)
+);
+
+CREATE INDEX IF NOT EXISTS event_outbox_archive_status_created_time_utc_index
+    on public.event_outbox_archive (status, created_time_utc);
+
+CREATE INDEX IF NOT EXISTS event_outbox_archive_event_idx1_index
+    on public.event_outbox_archive (event_group, event_topic, event_key);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS public.listener_dead_letter;
+
+COMMIT;
\ No newline at end of file
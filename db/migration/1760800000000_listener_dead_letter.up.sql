@@ -0,0 +1,24 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.listener_dead_letter (
+    dead_letter_id uuid not null,
+    message_uuid varchar(100) not null,
+    topic varchar(100) not null,
+    handler_name varchar(200) not null,
+    subscriber_name varchar(200) not null,
+    reason text not null,
+    payload bytea not null,
+    metadata jsonb not null,
+    created_time_utc timestamp not null,
+    replay_count integer not null default 0,
+    replayed_time_utc timestamp null,
+    constraint listener_dead_letter_pk primary key (dead_letter_id)
+);
+
+CREATE INDEX IF NOT EXISTS listener_dead_letter_topic_created_time_utc_index
+    on public.listener_dead_letter (topic, created_time_utc);
+
+CREATE INDEX IF NOT EXISTS listener_dead_letter_replayed_time_utc_index
+    on public.listener_dead_letter (replayed_time_utc);
+
+COMMIT;
\ No newline at end of file
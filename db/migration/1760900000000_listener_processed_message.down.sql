@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS public.listener_processed_message;
+
+COMMIT;
\ No newline at end of file
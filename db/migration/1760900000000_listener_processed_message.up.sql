@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.listener_processed_message (
+    consumer varchar(200) not null,
+    message_key varchar(100) not null,
+    processed_time_utc timestamp not null,
+    constraint listener_processed_message_pk primary key (consumer, message_key)
+);
+
+CREATE INDEX IF NOT EXISTS listener_processed_message_processed_time_utc_index
+    on public.listener_processed_message (processed_time_utc);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,9 @@
+BEGIN;
+
+COMMENT ON COLUMN public.event_outbox1.status IS NULL;
+COMMENT ON COLUMN public.event_outbox2.status IS NULL;
+COMMENT ON COLUMN public.event_outbox3.status IS NULL;
+COMMENT ON COLUMN public.event_outbox4.status IS NULL;
+COMMENT ON COLUMN public.event_outbox5.status IS NULL;
+
+COMMIT;
\ No newline at end of file
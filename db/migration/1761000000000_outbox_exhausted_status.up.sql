@@ -0,0 +1,12 @@
+BEGIN;
+
+-- EXHAUSTED is set by the cron relay of the outbox debugger on the PENDING or FAILED rows whose retry_count
+-- reached relay.maxRetries. The outbox library only relays PENDING and FAILED rows, so exhausted rows stay in
+-- place until requeued (requeue --status=EXHAUSTED --setStatus=PENDING) or purged.
+COMMENT ON COLUMN public.event_outbox1.status IS 'Relay status: PENDING or FAILED while relayed by the outbox library, EXHAUSTED once retry_count reached relay.maxRetries of the outbox debugger';
+COMMENT ON COLUMN public.event_outbox2.status IS 'Relay status: PENDING or FAILED while relayed by the outbox library, EXHAUSTED once retry_count reached relay.maxRetries of the outbox debugger';
+COMMENT ON COLUMN public.event_outbox3.status IS 'Relay status: PENDING or FAILED while relayed by the outbox library, EXHAUSTED once retry_count reached relay.maxRetries of the outbox debugger';
+COMMENT ON COLUMN public.event_outbox4.status IS 'Relay status: PENDING or FAILED while relayed by the outbox library, EXHAUSTED once retry_count reached relay.maxRetries of the outbox debugger';
+COMMENT ON COLUMN public.event_outbox5.status IS 'Relay status: PENDING or FAILED while relayed by the outbox library, EXHAUSTED once retry_count reached relay.maxRetries of the outbox debugger';
+
+COMMIT;
\ No newline at end of file
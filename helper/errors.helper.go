@@ -0,0 +1,219 @@
+package helper
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+/*
+ErrorClass tells processMessage what to do with a message whose processing failed.
+*/
+type ErrorClass string
+
+const (
+	ClassRetryable ErrorClass = "retryable" // Nack the message so the broker redelivers it; the default.
+	ClassPermanent ErrorClass = "permanent" // Ack and drop the message; a redelivery would fail the same way.
+	ClassPoison    ErrorClass = "poison"    // The message itself cannot be processed; return the error to the poison path.
+	ClassDuplicate ErrorClass = "duplicate" // The message was already processed; ack it.
+)
+
+/*
+Sentinel errors of the classes, matched with errors.Is on the errors returned by the handlers and processMessage.
+*/
+var (
+	ErrRetryable = errors.New("retryable error")
+	ErrPermanent = errors.New("permanent error")
+	ErrPoison    = errors.New("poison message")
+	ErrDuplicate = errors.New("duplicate message")
+)
+
+/*
+ClassifiedError is an error explicitly classified by a handler.
+It matches the sentinel error of its class with errors.Is and unwraps to the original error.
+*/
+type ClassifiedError struct {
+	Class ErrorClass // Class of the error.
+	Err   error      // Original error.
+}
+
+/*
+Error returns the message of the original error.
+*/
+func (e *ClassifiedError) Error() string {
+	if e.Err == nil {
+		return string(e.Class) + " error"
+	}
+	return e.Err.Error()
+}
+
+/*
+Unwrap returns the original error.
+*/
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+/*
+Is reports whether target is the sentinel error of the class.
+*/
+func (e *ClassifiedError) Is(target error) bool {
+	return target == sentinelErrors[e.Class]
+}
+
+// sentinelErrors maps every class to its sentinel error.
+var sentinelErrors = map[ErrorClass]error{
+	ClassRetryable: ErrRetryable,
+	ClassPermanent: ErrPermanent,
+	ClassPoison:    ErrPoison,
+	ClassDuplicate: ErrDuplicate,
+}
+
+/*
+Retryable classifies err as retryable, overriding the error rules: the message is redelivered.
+*/
+func Retryable(err error) error { return &ClassifiedError{Class: ClassRetryable, Err: err} }
+
+/*
+Permanent classifies err as permanent, overriding the error rules: the message is acked and dropped.
+*/
+func Permanent(err error) error { return &ClassifiedError{Class: ClassPermanent, Err: err} }
+
+/*
+Poison classifies err as caused by an unprocessable message, overriding the error rules.
+*/
+func Poison(err error) error { return &ClassifiedError{Class: ClassPoison, Err: err} }
+
+/*
+Duplicate classifies err as caused by an already processed message, overriding the error rules: the message is acked.
+*/
+func Duplicate(err error) error { return &ClassifiedError{Class: ClassDuplicate, Err: err} }
+
+/*
+ErrorRule maps the errors it matches to a class.
+*/
+type ErrorRule struct {
+	Name  string           // Name of the rule, logged with the classification.
+	Class ErrorClass       // Class of the matched errors.
+	Match func(error) bool // Reports whether the rule applies to the error.
+}
+
+/*
+MatchIs returns a rule matcher reporting whether the error wraps target, like errors.Is.
+Parameters:
+  - target: The error to look for in the chain.
+*/
+func MatchIs(target error) func(error) bool {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+/*
+MatchAs returns a rule matcher reporting whether the error chain holds an error of type E satisfying check,
+like errors.As. A nil check accepts every error of type E.
+Parameters:
+  - check: The condition on the error found in the chain.
+*/
+func MatchAs[E error](check func(E) bool) func(error) bool {
+	return func(err error) bool {
+		var target E
+		return errors.As(err, &target) && (check == nil || check(target))
+	}
+}
+
+/*
+MatchMessage returns a rule matcher reporting whether the error message contains substr.
+No built-in rule uses it: it is the explicit opt-in of listener.errorRules for errors that offer neither a sentinel
+nor a type to match.
+Parameters:
+  - substr: The text to look for in err.Error().
+*/
+func MatchMessage(substr string) func(error) bool {
+	return func(err error) bool { return strings.Contains(err.Error(), substr) }
+}
+
+/*
+MatchPostgresCode returns a rule matcher reporting whether the error chain holds a lib/pq error with one of the codes.
+Parameters:
+  - codes: The PostgreSQL error codes, e.g. "23505" for unique violations.
+*/
+func MatchPostgresCode(codes ...string) func(error) bool {
+	return MatchAs(func(err *pq.Error) bool {
+		for _, code := range codes {
+			if string(err.Code) == code {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+/*
+DefaultErrorRules returns the built-in rules:
+  - PostgreSQL unique violations (lib/pq code 23505) are duplicates.
+  - sql.ErrNoRows is permanent.
+
+Validation failures, which the listener used to ack by matching "tag validation failed" in the message, have no
+built-in rule: handlers return them wrapped with Permanent.
+*/
+func DefaultErrorRules() []ErrorRule {
+	return []ErrorRule{
+		{Name: "unique violation", Class: ClassDuplicate, Match: MatchPostgresCode("23505")},
+		{Name: "no rows", Class: ClassPermanent, Match: MatchIs(sql.ErrNoRows)},
+	}
+}
+
+var (
+	errorRulesMu sync.RWMutex          // Guards errorRules.
+	errorRules   = DefaultErrorRules() // Rules applied by Classify, in order.
+)
+
+/*
+SetErrorRules replaces the rules applied by Classify to the errors that were not classified explicitly.
+Parameters:
+  - rules: The rules, tried in order; the first match wins. DefaultErrorRules restores the built-in ones.
+*/
+func SetErrorRules(rules ...ErrorRule) {
+	errorRulesMu.Lock()
+	defer errorRulesMu.Unlock()
+	errorRules = append([]ErrorRule(nil), rules...)
+}
+
+/*
+Classify returns the class of an error.
+Parameters:
+  - err: The error returned by the handler.
+
+Behavior:
+  - An explicit classification (Retryable, Permanent, Poison, Duplicate or a wrapped sentinel error) wins.
+  - Otherwise the first matching error rule decides.
+  - Otherwise the error is retryable.
+
+Returns:
+  - The class and the name of the deciding rule ("explicit" or "default" when no rule matched).
+*/
+func Classify(err error) (ErrorClass, string) {
+	// Honor the explicit classification of the handler
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Class, "explicit"
+	}
+	for _, class := range []ErrorClass{ClassPoison, ClassPermanent, ClassDuplicate, ClassRetryable} {
+		if errors.Is(err, sentinelErrors[class]) {
+			return class, "explicit"
+		}
+	}
+
+	// Apply the error rules in order
+	errorRulesMu.RLock()
+	defer errorRulesMu.RUnlock()
+	for _, rule := range errorRules {
+		if rule.Match(err) {
+			return rule.Class, rule.Name
+		}
+	}
+
+	return ClassRetryable, "default"
+}
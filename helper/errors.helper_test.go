@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestClassify(t *testing.T) {
+	errInsufficient := errors.New("insufficient balance")
+	custom := []ErrorRule{
+		{Name: "insufficient balance", Class: ClassPoison, Match: MatchIs(errInsufficient)},
+		{Name: "no rows as duplicate", Class: ClassDuplicate, Match: MatchIs(sql.ErrNoRows)},
+	}
+
+	tests := []struct {
+		name      string
+		rules     []ErrorRule
+		err       error
+		wantClass ErrorClass
+		wantRule  string
+	}{
+		{"explicit permanent", DefaultErrorRules(), Permanent(errors.New("bad event")), ClassPermanent, "explicit"},
+		{"explicit poison", DefaultErrorRules(), Poison(errors.New("bad payload")), ClassPoison, "explicit"},
+		{"explicit duplicate", DefaultErrorRules(), Duplicate(errors.New("seen")), ClassDuplicate, "explicit"},
+		{"explicit retryable beats rules", DefaultErrorRules(), Retryable(sql.ErrNoRows), ClassRetryable, "explicit"},
+		{"wrapped explicit", DefaultErrorRules(), fmt.Errorf("handle: %w", Permanent(errors.New("bad event"))), ClassPermanent, "explicit"},
+		{"wrapped sentinel", DefaultErrorRules(), fmt.Errorf("handle: %w", ErrPoison), ClassPoison, "explicit"},
+		{"wrapped duplicate sentinel", DefaultErrorRules(), fmt.Errorf("insert: %w", ErrDuplicate), ClassDuplicate, "explicit"},
+		{"unique violation", DefaultErrorRules(), &pq.Error{Code: "23505"}, ClassDuplicate, "unique violation"},
+		{"wrapped unique violation", DefaultErrorRules(), fmt.Errorf("insert: %w", &pq.Error{Code: "23505"}), ClassDuplicate, "unique violation"},
+		{"other postgres code", DefaultErrorRules(), &pq.Error{Code: "40001"}, ClassRetryable, "default"},
+		{"no rows", DefaultErrorRules(), fmt.Errorf("load order: %w", sql.ErrNoRows), ClassPermanent, "no rows"},
+		{"tag validation message is not matched", DefaultErrorRules(), errors.New("tag validation failed"), ClassRetryable, "default"},
+		{"unmatched", DefaultErrorRules(), errors.New("connection reset"), ClassRetryable, "default"},
+		{"configured rule", append(custom, DefaultErrorRules()...), fmt.Errorf("debit: %w", errInsufficient), ClassPoison, "insufficient balance"},
+		{"configured rule before built-in", append(custom, DefaultErrorRules()...), sql.ErrNoRows, ClassDuplicate, "no rows as duplicate"},
+		{"built-in rule after configured", append(custom, DefaultErrorRules()...), &pq.Error{Code: "23505"}, ClassDuplicate, "unique violation"},
+		{"message opt-in", []ErrorRule{{Name: "validation", Class: ClassPermanent, Match: MatchMessage("tag validation failed")}}, errors.New("Key: tag validation failed"), ClassPermanent, "validation"},
+	}
+
+	t.Cleanup(func() { SetErrorRules(DefaultErrorRules()...) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetErrorRules(tt.rules...)
+			class, rule := Classify(tt.err)
+			if class != tt.wantClass || rule != tt.wantRule {
+				t.Errorf("Classify(%v) = %s, %q; want %s, %q", tt.err, class, rule, tt.wantClass, tt.wantRule)
+			}
+		})
+	}
+}
@@ -3,23 +3,69 @@ package helper
 import (
 	"context"
 	"encoding/json"
-	"strings"
+	"outbox/debugger/broker"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
 	"github.com/rs/zerolog/log"
 )
 
+/*
+MessageMeta is the typed view of the delivery information of a message.
+*/
+type MessageMeta struct {
+	UUID          string           // UUID of the message.
+	Topic         string           // Topic the message was consumed from, empty outside a router handler.
+	Handler       string           // Name of the router handler, empty outside a router handler.
+	OrderingKey   string           // Value of the ordering_key metadata.
+	CorrelationID string           // Value of the correlation_id metadata set by the CorrelationID middleware.
+	PublishedAt   time.Time        // Value of the published_at metadata, zero if missing or invalid.
+	Metadata      message.Metadata // Every metadata of the message.
+}
+
+/*
+NewMessageMeta reads the delivery information of a message.
+Parameters:
+  - msg: The delivered message.
+*/
+func NewMessageMeta(msg *message.Message) MessageMeta {
+	publishedAt, _ := time.Parse(time.RFC3339Nano, msg.Metadata.Get(broker.PublishedAtMetadata))
+	return MessageMeta{
+		UUID:          msg.UUID,
+		Topic:         message.SubscribeTopicFromCtx(msg.Context()),
+		Handler:       message.HandlerNameFromCtx(msg.Context()),
+		OrderingKey:   msg.Metadata.Get(broker.OrderingKeyMetadata),
+		CorrelationID: middleware.MessageCorrelationID(msg),
+		PublishedAt:   publishedAt,
+		Metadata:      msg.Metadata,
+	}
+}
+
 /*
 WrapProcessMessages processes messages from a channel, handles payloads, and logs the progress.
 Parameters:
   - messages: Channel from which messages are received.
-  - handlerFunc: Function to handle the unmarshaled payload.
+  - handlerFunc: Function to handle the unmarshaled payload, called with the context of the message.
   - spanName: Name of the tracing span for observability.
   - note: see the config router wtermill for now retry count if msg error and this is auto ack if use router
 */
 func WrapProcessMessages[T any](msg *message.Message, handlerFunc func(ctx context.Context, payload T) error, spanName string) error {
 	// Process each message from the channel
-	return processMessage(context.Background(), msg, handlerFunc, spanName)
+	return processMessage(msg.Context(), msg, func(ctx context.Context, _ MessageMeta, payload T) error {
+		return handlerFunc(ctx, payload)
+	}, spanName)
+}
+
+/*
+WrapProcessMessagesWithMeta is WrapProcessMessages handing the handler the delivery information of the message too.
+Parameters:
+  - msg: The message to be processed.
+  - handlerFunc: Function to handle the unmarshaled payload, called with the context and the metadata of the message.
+  - spanName: Name of the tracing span for observability.
+*/
+func WrapProcessMessagesWithMeta[T any](msg *message.Message, handlerFunc func(ctx context.Context, meta MessageMeta, payload T) error, spanName string) error {
+	return processMessage(msg.Context(), msg, handlerFunc, spanName)
 }
 
 /*
@@ -27,63 +73,80 @@ processMessage processes a single message, error handling, and logging.
 Parameters:
   - ctx: Context for tracing and logging.
   - msg: The message to be processed.
-  - handlerFunc: Function to handle the unmarshaled payload.
+  - handlerFunc: Function to handle the unmarshaled payload with the metadata of the message.
   - spanName: Name of the tracing span for observability.
+
+Error Handling:
+  - A payload that cannot be unmarshaled returns an error matching ErrPoison.
+  - Handler errors are classified by Classify: permanent and duplicate errors ack the message (nil is returned),
+    poison errors are returned matching ErrPoison and retryable errors are returned as is for a redelivery.
 */
-func processMessage[T any](ctx context.Context, msg *message.Message, handlerFunc func(ctx context.Context, payload T) error, spanName string) error {
-	// Unmarshal the message payload
+func processMessage[T any](ctx context.Context, msg *message.Message, handlerFunc func(ctx context.Context, meta MessageMeta, payload T) error, spanName string) error {
+	meta := NewMessageMeta(msg)
+
+	// Unmarshal the message payload; a payload that cannot be decoded never will be
 	var payload T
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
-		logErrorPayload(msg.Payload, err)
-		return err
+		logErrorPayload(meta, msg.Payload, err)
+		return Poison(err)
 	}
 
 	// Log the message handling start
-	logMessage("PROCESS HANDLE MESSAGE", payload, spanName)
+	logMessage("PROCESS HANDLE MESSAGE", meta, payload, spanName)
 
 	// Handle the unmarshaled payload
-	if err := handlerFunc(ctx, payload); err != nil {
-		logErrorHanldeFunc(err)
-		// ignored error for no retry msg
-		if strings.Contains(err.Error(), "duplicate key value") ||
-			strings.Contains(err.Error(), "sql: no rows in result set") ||
-			strings.Contains(err.Error(), "tag validation failed") {
+	if err := handlerFunc(ctx, meta, payload); err != nil {
+		class, rule := Classify(err)
+		logErrorHanldeFunc(meta, err, class, rule)
+		switch class {
+		case ClassPermanent, ClassDuplicate:
+			// ack the msg, a retry would fail the same way
 			return nil
+		case ClassPoison:
+			return Poison(err)
+		default:
+			return err
 		}
-		return err
 	}
 
 	// Log the message handling completion
-	logMessage("DONE HANDLE MESSAGE", payload, spanName)
+	logMessage("DONE HANDLE MESSAGE", meta, payload, spanName)
 	return nil
 }
 
 /*
 logErrorPayload logs an error encountered during payload unmarshaling.
 Parameters:
+  - meta: The delivery information of the message.
   - payload: The raw payload data.
   - err: The error encountered during unmarshaling.
 */
-func logErrorPayload(payload []byte, err error) {
-	log.Error().Interface("[ERROR PAYLOAD]", string(payload)).Msgf("Error unmarshaling payload: %s", err.Error())
+func logErrorPayload(meta MessageMeta, payload []byte, err error) {
+	log.Error().Interface("[ERROR PAYLOAD]", string(payload)).Str("uuid", meta.UUID).Str("topic", meta.Topic).
+		Str("correlation_id", meta.CorrelationID).Msgf("Error unmarshaling payload: %s", err.Error())
 }
 
 /*
 logErrorHanldeMessage logs an error encountered during message handling.
 Parameters:
+  - meta: The delivery information of the message.
   - err: The error encountered during message handling.
+  - class: The class of the error.
+  - rule: The rule that classified the error.
 */
-func logErrorHanldeFunc(err error) {
-	log.Error().Msgf("[ERROR HANDLE MESSAGE]: %s", err.Error())
+func logErrorHanldeFunc(meta MessageMeta, err error, class ErrorClass, rule string) {
+	log.Error().Str("uuid", meta.UUID).Str("topic", meta.Topic).Str("correlation_id", meta.CorrelationID).Msgf("[ERROR HANDLE MESSAGE] (%s, %s): %s", class, rule, err.Error())
 }
 
 /*
 logMessage logs a message with the provided stage and span name for observability.
 Parameters:
   - stage: The current stage of message processing.
+  - meta: The delivery information of the message.
   - payload: The unmarshaled payload data.
   - spanName: The name of the tracing span.
 */
-func logMessage[T any](stage string, payload T, spanName string) {
-	log.Info().Interface("["+stage+"]", payload).Msg(spanName)
+func logMessage[T any](stage string, meta MessageMeta, payload T, spanName string) {
+	log.Info().Interface("["+stage+"]", payload).Str("uuid", meta.UUID).Str("topic", meta.Topic).
+		Str("ordering_key", meta.OrderingKey).Str("correlation_id", meta.CorrelationID).Msg(spanName)
 }
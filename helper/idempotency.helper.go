@@ -0,0 +1,266 @@
+package helper
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/rs/zerolog/log"
+)
+
+/*
+IdempotencyStore records the keys of the messages processed by a consumer.
+*/
+type IdempotencyStore interface {
+	// Seen reports whether the key was recorded as processed.
+	Seen(ctx context.Context, key string) (bool, error)
+	// MarkProcessed records the key as processed; recording a key twice is not an error.
+	MarkProcessed(ctx context.Context, key string) error
+}
+
+/*
+MessageUUIDKey is the idempotency key of a message made of its UUID.
+*/
+func MessageUUIDKey(msg *message.Message) string {
+	return msg.UUID
+}
+
+/*
+PayloadHashKey is the idempotency key of a message made of the SHA-256 of its payload,
+for publishers giving every redelivery of the same event a new UUID.
+*/
+func PayloadHashKey(msg *message.Message) string {
+	sum := sha256.Sum256(msg.Payload)
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+Idempotency skips the messages whose key was already processed and counts them per topic.
+*/
+type Idempotency struct {
+	store IdempotencyStore                  // Store of the processed keys.
+	key   func(msg *message.Message) string // Key of a message; an empty key disables the check for the message.
+
+	mu         sync.Mutex       // Guards duplicates.
+	duplicates map[string]int64 // Number of duplicates skipped per topic.
+}
+
+/*
+NewIdempotency creates the idempotency layer of a consumer.
+Parameters:
+  - store: The store of the processed keys.
+  - key: The key of a message, e.g. MessageUUIDKey or PayloadHashKey.
+*/
+func NewIdempotency(store IdempotencyStore, key func(msg *message.Message) string) *Idempotency {
+	return &Idempotency{store: store, key: key, duplicates: map[string]int64{}}
+}
+
+/*
+Close releases the store of the processed keys if it holds resources, such as the connection pool of
+SQLIdempotencyStore. A nil Idempotency has nothing to close.
+*/
+func (i *Idempotency) Close() error {
+	if i == nil {
+		return nil
+	}
+	if closer, ok := i.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+/*
+Duplicates returns the number of duplicates skipped per topic.
+*/
+func (i *Idempotency) Duplicates() map[string]int64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	duplicates := make(map[string]int64, len(i.duplicates))
+	for topic, count := range i.duplicates {
+		duplicates[topic] = count
+	}
+	return duplicates
+}
+
+/*
+WrapProcessMessagesIdempotent is WrapProcessMessages skipping the messages already processed.
+Parameters:
+  - msg: The message to be processed.
+  - idempotency: The idempotency layer of the consumer; nil processes every message.
+  - handlerFunc: Function to handle the unmarshaled payload.
+  - spanName: Name of the tracing span for observability.
+
+Behavior:
+  - A message whose key was already processed is counted as duplicate and acked without calling the handler.
+  - The key is recorded once the message was processed without error, so failed messages can be redelivered.
+  - Two deliveries of the same message processed concurrently may both reach the handler.
+
+Error Handling:
+  - A store lookup failure returns the error so the message is redelivered.
+  - A failure to record the key is logged; the message was processed, so it is not redelivered.
+*/
+func WrapProcessMessagesIdempotent[T any](msg *message.Message, idempotency *Idempotency, handlerFunc func(ctx context.Context, payload T) error, spanName string) error {
+	return idempotency.process(msg, spanName, func() error {
+		return WrapProcessMessages(msg, handlerFunc, spanName)
+	})
+}
+
+/*
+WrapProcessMessagesIdempotentWithMeta is WrapProcessMessagesWithMeta skipping the messages already processed,
+like WrapProcessMessagesIdempotent.
+Parameters:
+  - msg: The message to be processed.
+  - idempotency: The idempotency layer of the consumer; nil processes every message.
+  - handlerFunc: Function to handle the unmarshaled payload with the metadata of the message.
+  - spanName: Name of the tracing span for observability.
+*/
+func WrapProcessMessagesIdempotentWithMeta[T any](msg *message.Message, idempotency *Idempotency, handlerFunc func(ctx context.Context, meta MessageMeta, payload T) error, spanName string) error {
+	return idempotency.process(msg, spanName, func() error {
+		return WrapProcessMessagesWithMeta(msg, handlerFunc, spanName)
+	})
+}
+
+/*
+process runs processFunc unless the message was already processed, then records its key.
+Parameters:
+  - msg: The message to be processed.
+  - spanName: Name of the tracing span for observability.
+  - processFunc: Function processing the message.
+*/
+func (i *Idempotency) process(msg *message.Message, spanName string, processFunc func() error) error {
+	if i == nil {
+		return processFunc()
+	}
+	key := i.key(msg)
+	if key == "" {
+		return processFunc()
+	}
+
+	// Skip the message if it was already processed
+	seen, err := i.store.Seen(msg.Context(), key)
+	if err != nil {
+		return fmt.Errorf("lookup idempotency key %s: %w", key, err)
+	}
+	if seen {
+		topic := message.SubscribeTopicFromCtx(msg.Context())
+		i.mu.Lock()
+		i.duplicates[topic]++
+		i.mu.Unlock()
+		log.Warn().Msgf("[DUPLICATE MESSAGE] %s (key %s, topic %s) was already processed: %s", msg.UUID, key, topic, spanName)
+		return nil
+	}
+
+	// Process the message, then record its key
+	if err := processFunc(); err != nil {
+		return err
+	}
+	if err := i.store.MarkProcessed(msg.Context(), key); err != nil {
+		log.Error().Msgf("[ERROR IDEMPOTENCY] Could not record key %s of message %s: %s", key, msg.UUID, err.Error())
+	}
+	return nil
+}
+
+/*
+MemoryIdempotencyStore keeps the most recently processed keys in memory, evicting the least recently used ones.
+*/
+type MemoryIdempotencyStore struct {
+	mu       sync.Mutex               // Guards every field below.
+	capacity int                      // Maximum number of keys kept.
+	order    *list.List               // Keys, most recently used first.
+	entries  map[string]*list.Element // Element of every key in order.
+}
+
+/*
+NewMemoryIdempotencyStore creates an in-memory store.
+Parameters:
+  - capacity: The maximum number of keys kept; duplicates of evicted keys are not detected.
+*/
+func NewMemoryIdempotencyStore(capacity int) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{capacity: capacity, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+/*
+Seen reports whether the key is kept, marking it as recently used.
+*/
+func (s *MemoryIdempotencyStore) Seen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if ok {
+		s.order.MoveToFront(element)
+	}
+	return ok, nil
+}
+
+/*
+MarkProcessed keeps the key, evicting the least recently used key when the store is full.
+*/
+func (s *MemoryIdempotencyStore) MarkProcessed(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.order.MoveToFront(element)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(key)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(string))
+	}
+	return nil
+}
+
+/*
+SQLIdempotencyStore records the processed keys of a consumer in the listener_processed_message table,
+so they survive restarts and are shared by the instances of the consumer.
+*/
+type SQLIdempotencyStore struct {
+	db       *sql.DB // Connection pool of the database holding the table.
+	consumer string  // Name of the consumer owning the keys.
+}
+
+/*
+NewSQLIdempotencyStore creates a store backed by the listener_processed_message table.
+Parameters:
+  - db: The connection pool of the database holding the table.
+  - consumer: The name of the consumer, e.g. its subscription; every consumer has its own keys.
+*/
+func NewSQLIdempotencyStore(db *sql.DB, consumer string) *SQLIdempotencyStore {
+	return &SQLIdempotencyStore{db: db, consumer: consumer}
+}
+
+/*
+Seen reports whether the key was recorded for the consumer.
+*/
+func (s *SQLIdempotencyStore) Seen(ctx context.Context, key string) (bool, error) {
+	var seen bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (
+		SELECT 1 FROM listener_processed_message WHERE consumer = $1 AND message_key = $2)`, s.consumer, key).Scan(&seen)
+	return seen, err
+}
+
+/*
+MarkProcessed records the key for the consumer, ignoring keys already recorded.
+*/
+func (s *SQLIdempotencyStore) MarkProcessed(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO listener_processed_message (consumer, message_key, processed_time_utc)
+		VALUES ($1, $2, now() at time zone 'utc') ON CONFLICT (consumer, message_key) DO NOTHING`, s.consumer, key)
+	return err
+}
+
+/*
+Close closes the connection pool of the store.
+*/
+func (s *SQLIdempotencyStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,52 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file opens the direct database connection used to query the outbox tables.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"outbox/debugger/config"
+	"outbox/debugger/enum"
+	"time"
+
+	_ "github.com/lib/pq" // PostgreSQL driver for database/sql.
+)
+
+// openOutboxDB opens a database/sql connection pool to the outbox database.
+//
+// Parameters:
+//   - ctx: The context bounding the connectivity check.
+//   - cfg: The runtime configuration providing the database settings.
+//
+// Returns:
+//   - The opened connection pool; the caller must close it.
+//   - An error if the database cannot be reached.
+func openOutboxDB(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	// Step 1: Open the connection pool with the configured limits.
+	db, err := sql.Open(enum.DbSqlDriverName, cfg.Database.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("open outbox database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConnections)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConnections)
+	db.SetConnMaxLifetime(time.Duration(cfg.Database.ConnectionMaxLifetime) * time.Second)
+
+	// Step 2: Verify the database is reachable.
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect outbox database: %w", err)
+	}
+
+	return db, nil
+}
+
+// outboxTableName returns the name of the outbox table with the given index.
+func outboxTableName(index int) string {
+	return fmt.Sprintf("event_outbox%d", index)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
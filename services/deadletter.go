@@ -0,0 +1,320 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines the dead-letter path of the listener: where poison messages are forwarded, and how they are listed and replayed.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"outbox/debugger/broker"
+	"outbox/debugger/config"
+	"outbox/debugger/helper"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// deadLetterTable is the table storing the poison messages with the table dead-letter target.
+const deadLetterTable = "listener_dead_letter"
+
+// poisonMetadataKeys are the metadata keys set by the poison queue middleware, removed from replayed messages.
+var poisonMetadataKeys = []string{
+	middleware.ReasonForPoisonedKey,
+	middleware.PoisonedTopicKey,
+	middleware.PoisonedHandlerKey,
+	middleware.PoisonedSubscriberKey,
+}
+
+// DeadLetter is a poison message stored in the listener_dead_letter table.
+type DeadLetter struct {
+	DeadLetterID    string            `json:"deadLetterId"`    // Primary key of the row.
+	MessageUUID     string            `json:"messageUuid"`     // UUID of the poison message.
+	Topic           string            `json:"topic"`           // Topic the message was consumed from.
+	HandlerName     string            `json:"handlerName"`     // Router handler that failed to process the message.
+	SubscriberName  string            `json:"subscriberName"`  // Subscriber that delivered the message.
+	Reason          string            `json:"reason"`          // Error returned by the handler.
+	Payload         []byte            `json:"-"`               // Raw message payload.
+	Metadata        map[string]string `json:"metadata"`        // Message metadata, without the poison queue keys.
+	CreatedTimeUTC  time.Time         `json:"createdTimeUtc"`  // Time the message was dead-lettered.
+	ReplayCount     int               `json:"replayCount"`     // Number of times the message was replayed.
+	ReplayedTimeUTC *time.Time        `json:"replayedTimeUtc"` // Time of the last replay, nil if never replayed.
+}
+
+// DecodedPayload returns the message payload in its most readable form, like OutboxRow.DecodedMessage.
+func (d DeadLetter) DecodedPayload() any {
+	return decodePayload(d.Payload)
+}
+
+// DeadLetterFilter selects rows of the listener_dead_letter table.
+// Zero values leave the corresponding criterion unset.
+type DeadLetterFilter struct {
+	IDs             []string  // Accepted values of dead_letter_id.
+	Topics          []string  // Accepted values of topic.
+	CreatedFrom     time.Time // Inclusive lower bound of created_time_utc.
+	CreatedTo       time.Time // Exclusive upper bound of created_time_utc.
+	IncludeReplayed bool      // Also select the messages already replayed.
+	Limit           int       // Maximum number of rows returned; 0 means unlimited.
+}
+
+// where builds the parameterized WHERE clause of the filter.
+func (f DeadLetterFilter) where() (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if len(f.IDs) > 0 {
+		add("dead_letter_id = ANY($%d::uuid[])", pq.Array(f.IDs))
+	}
+	if len(f.Topics) > 0 {
+		add("topic = ANY($%d)", pq.Array(f.Topics))
+	}
+	if !f.CreatedFrom.IsZero() {
+		add("created_time_utc >= $%d", f.CreatedFrom.UTC())
+	}
+	if !f.CreatedTo.IsZero() {
+		add("created_time_utc < $%d", f.CreatedTo.UTC())
+	}
+	if !f.IncludeReplayed {
+		conditions = append(conditions, "replayed_time_utc IS NULL")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// NewDeadLetterMiddleware creates the middleware forwarding the poison messages of the listener to the dead-letter target.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the dead-letter, broker and database settings.
+//   - logger: The Watermill logger used by the broker publisher.
+//
+// Behavior:
+//   - Forwards every message whose handler error matches helper.ErrPoison, with the reason, topic, handler and
+//     subscriber set in the reason_poisoned, topic_poisoned, handler_poisoned and subscriber_poisoned metadata,
+//     then acks it. Any other error is left to the router.
+//   - The topic target publishes to listener.deadLetter.topic on the configured broker; the table target inserts
+//     into the listener_dead_letter table whatever listener.deadLetter.topic is. The publisher lives as long as the process.
+//   - A message that cannot be forwarded is nacked with both errors.
+//
+// Returns:
+//   - The middleware, or nil with the none target.
+//   - An error if the publisher of the target cannot be created.
+func NewDeadLetterMiddleware(cfg *config.Config, logger watermill.LoggerAdapter) (message.HandlerMiddleware, error) {
+	// Step 1: Create the publisher of the dead-letter target.
+	var publisher message.Publisher
+	poisonTopic := cfg.Listener.DeadLetter.Topic
+	switch cfg.Listener.DeadLetter.Target {
+	case config.DeadLetterTopic:
+		brokerPublisher, err := broker.NewPublisher(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("create dead-letter publisher: %w", err)
+		}
+		publisher = brokerPublisher
+		log.Info().Msgf("[DeadLetter] Poison messages are published to topic %s", cfg.Listener.DeadLetter.Topic)
+	case config.DeadLetterTable:
+		db, err := openOutboxDB(context.Background(), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("create dead-letter publisher: %w", err)
+		}
+		publisher = &deadLetterTablePublisher{db: db}
+		poisonTopic = deadLetterTable // The table publisher ignores the topic, which the poison queue requires anyway.
+		log.Info().Msgf("[DeadLetter] Poison messages are stored in table %s", deadLetterTable)
+	default:
+		return nil, nil
+	}
+
+	// Step 2: Forward only the poison messages.
+	return middleware.PoisonQueueWithFilter(publisher, poisonTopic, func(err error) bool {
+		return errors.Is(err, helper.ErrPoison)
+	})
+}
+
+// deadLetterTablePublisher is the publisher of the table dead-letter target, inserting the poison messages into listener_dead_letter.
+type deadLetterTablePublisher struct {
+	db *sql.DB // Connection pool of the outbox database.
+}
+
+// Publish inserts every message with its poison queue metadata; the topic is ignored.
+func (p *deadLetterTablePublisher) Publish(_ string, messages ...*message.Message) error {
+	for _, msg := range messages {
+		// Split the poison queue metadata from the metadata of the message
+		metadata := make(map[string]string, len(msg.Metadata))
+		for key, value := range msg.Metadata {
+			metadata[key] = value
+		}
+		for _, key := range poisonMetadataKeys {
+			delete(metadata, key)
+		}
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("encode metadata of message %s: %w", msg.UUID, err)
+		}
+
+		// Store the message
+		_, err = p.db.ExecContext(msg.Context(), fmt.Sprintf(`
+			INSERT INTO %s (dead_letter_id, message_uuid, topic, handler_name, subscriber_name, reason, payload, metadata, created_time_utc)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, deadLetterTable),
+			uuid.NewString(), msg.UUID, msg.Metadata.Get(middleware.PoisonedTopicKey), msg.Metadata.Get(middleware.PoisonedHandlerKey),
+			msg.Metadata.Get(middleware.PoisonedSubscriberKey), msg.Metadata.Get(middleware.ReasonForPoisonedKey),
+			[]byte(msg.Payload), encoded, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("store dead letter of message %s: %w", msg.UUID, err)
+		}
+		log.Warn().Msgf("[DeadLetter] Message %s of topic %s stored in %s: %s", msg.UUID,
+			msg.Metadata.Get(middleware.PoisonedTopicKey), deadLetterTable, msg.Metadata.Get(middleware.ReasonForPoisonedKey))
+	}
+	return nil
+}
+
+// Close closes the connection pool.
+func (p *deadLetterTablePublisher) Close() error {
+	return p.db.Close()
+}
+
+// ListDeadLetters lists the dead letters matching the filter, oldest first.
+//
+// Parameters:
+//   - ctx: The context for the query.
+//   - cfg: The runtime configuration providing the database settings.
+//   - filter: The criteria selecting the rows.
+//
+// Returns:
+//   - The matching dead letters.
+//   - An error if the query fails.
+func ListDeadLetters(ctx context.Context, cfg *config.Config, filter DeadLetterFilter) ([]DeadLetter, error) {
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return selectDeadLetters(ctx, db, filter)
+}
+
+// selectDeadLetters runs the query of the filter on the listener_dead_letter table.
+func selectDeadLetters(ctx context.Context, q queryer, filter DeadLetterFilter) ([]DeadLetter, error) {
+	// Step 1: Build the query.
+	where, args := filter.where()
+	query := fmt.Sprintf(`SELECT dead_letter_id, message_uuid, topic, handler_name, subscriber_name, reason, payload, metadata,
+		created_time_utc, replay_count, replayed_time_utc FROM %s%s ORDER BY created_time_utc`, deadLetterTable, where)
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	// Step 2: Scan the rows.
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", deadLetterTable, err)
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var (
+			letter   DeadLetter
+			metadata []byte
+			replayed sql.NullTime
+		)
+		if err := rows.Scan(&letter.DeadLetterID, &letter.MessageUUID, &letter.Topic, &letter.HandlerName, &letter.SubscriberName,
+			&letter.Reason, &letter.Payload, &metadata, &letter.CreatedTimeUTC, &letter.ReplayCount, &replayed); err != nil {
+			return nil, fmt.Errorf("scan %s: %w", deadLetterTable, err)
+		}
+		if err := json.Unmarshal(metadata, &letter.Metadata); err != nil {
+			return nil, fmt.Errorf("decode metadata of dead letter %s: %w", letter.DeadLetterID, err)
+		}
+		if replayed.Valid {
+			letter.ReplayedTimeUTC = &replayed.Time
+		}
+		letters = append(letters, letter)
+	}
+	return letters, rows.Err()
+}
+
+// DeadLetterReplayResult reports the outcome of a replay.
+type DeadLetterReplayResult struct {
+	Selected []DeadLetter // Dead letters matching the filter.
+	Replayed int          // Number of dead letters published again.
+}
+
+// ReplayDeadLetters publishes the dead letters matching the filter again to the topic they were consumed from.
+//
+// Parameters:
+//   - ctx: The context for the queries.
+//   - cfg: The runtime configuration providing the database and broker settings.
+//   - filter: The criteria selecting the rows.
+//   - toTopic: The topic to publish to instead of the original topic, if not empty.
+//   - dryRun: Only select the rows.
+//
+// Behavior:
+//   - Publishes every message with the publisher of the configured broker, keeping its UUID and metadata and
+//     stamping a new publish time.
+//   - Marks every published row as replayed; the rows are kept so the replays can be audited.
+//   - Stops at the first message that cannot be published; the rows published before stay marked.
+//
+// Returns:
+//   - The selected rows and the number of replayed messages.
+//   - An error if a query or a publish fails.
+func ReplayDeadLetters(ctx context.Context, cfg *config.Config, filter DeadLetterFilter, toTopic string, dryRun bool) (DeadLetterReplayResult, error) {
+	// Step 1: Select the dead letters to replay.
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return DeadLetterReplayResult{}, err
+	}
+	defer db.Close()
+
+	letters, err := selectDeadLetters(ctx, db, filter)
+	if err != nil {
+		return DeadLetterReplayResult{}, err
+	}
+	result := DeadLetterReplayResult{Selected: letters}
+	if dryRun || len(letters) == 0 {
+		return result, nil
+	}
+
+	// Step 2: Create the publisher of the configured broker.
+	publisher, err := broker.NewPublisher(cfg, watermill.NewStdLogger(false, false))
+	if err != nil {
+		return result, err
+	}
+	publisher = broker.WithPublishTimestamp(publisher)
+	defer publisher.Close()
+
+	// Step 3: Publish every message and mark its row as replayed.
+	for _, letter := range letters {
+		topic := letter.Topic
+		if toTopic != "" {
+			topic = toTopic
+		}
+		msg := message.NewMessage(letter.MessageUUID, letter.Payload)
+		for key, value := range letter.Metadata {
+			msg.Metadata.Set(key, value)
+		}
+		if err := publisher.Publish(topic, msg); err != nil {
+			return result, fmt.Errorf("replay dead letter %s to %s: %w", letter.DeadLetterID, topic, err)
+		}
+
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s SET replay_count = replay_count + 1, replayed_time_utc = $1 WHERE dead_letter_id = $2`, deadLetterTable),
+			time.Now().UTC(), letter.DeadLetterID); err != nil {
+			return result, fmt.Errorf("mark dead letter %s as replayed: %w", letter.DeadLetterID, err)
+		}
+		result.Replayed++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,252 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file tracks the deliveries seen by the listener to detect missing, duplicated and out-of-order events.
+package services
+
+import (
+	"sort"
+	"sync"
+)
+
+// DeliveryTracker records the sequence numbers delivered to the listener per publisher run, topic and ordering key.
+// It is safe for concurrent use by the router handlers.
+type DeliveryTracker struct {
+	mu        sync.Mutex                     // Guards every field below.
+	keys      map[deliveryKey]*keyDeliveries // Deliveries per publisher run, topic and ordering key.
+	corrupted int                            // Deliveries whose checksum did not match.
+	doomed    []DoomedDelivery               // Deliveries of events whose transaction was rolled back.
+	unique    int                            // Distinct (run, topic, ordering key, sequence) events delivered.
+	expected  int                            // Number of events the publisher produced, 0 when unknown.
+	done      chan struct{}                  // Closed once every expected event was delivered.
+	doneOnce  sync.Once                      // Guards the closing of done.
+}
+
+// deliveryKey identifies the sequence space of the events of one publisher run, topic and ordering key.
+type deliveryKey struct {
+	runID       string // Identifier of the publisher run.
+	topic       string // Topic of the events.
+	orderingKey string // Ordering key of the events.
+}
+
+// keyDeliveries holds the deliveries of one publisher run, topic and ordering key.
+type keyDeliveries struct {
+	counts     map[int]int          // Number of deliveries per sequence number.
+	highest    int                  // Highest sequence number delivered so far.
+	delivered  int                  // Number of deliveries, duplicates included.
+	outOfOrder []OutOfOrderDelivery // Deliveries that arrived after a higher sequence number.
+}
+
+// OutOfOrderDelivery describes an event delivered after a later event of the same topic and ordering key.
+type OutOfOrderDelivery struct {
+	Sequence int // Sequence number of the late event.
+	After    int // Highest sequence number delivered before it.
+}
+
+// DoomedDelivery describes the delivery of an event whose transaction was deliberately rolled back.
+type DoomedDelivery struct {
+	RunID       string // Identifier of the publisher run.
+	Topic       string // Topic of the event.
+	OrderingKey string // Ordering key of the event.
+	Sequence    int    // Sequence number of the event in the doomed sequence space.
+}
+
+// KeyDeliveryReport summarizes the deliveries of one publisher run, topic and ordering key.
+type KeyDeliveryReport struct {
+	RunID       string               // Identifier of the publisher run.
+	Topic       string               // Topic of the events.
+	OrderingKey string               // Ordering key, empty when the events had none.
+	Delivered   int                  // Number of deliveries, duplicates included.
+	Unique      int                  // Number of distinct sequence numbers delivered.
+	Highest     int                  // Highest sequence number delivered.
+	Missing     []int                // Sequence numbers below Highest that were never delivered.
+	Duplicates  map[int]int          // Number of extra deliveries per duplicated sequence number.
+	OutOfOrder  []OutOfOrderDelivery // Deliveries that arrived after a higher sequence number.
+}
+
+// DeliveryReport summarizes every delivery seen by the listener.
+type DeliveryReport struct {
+	Keys      []KeyDeliveryReport // Reports per publisher run, topic and ordering key, sorted by run, topic and key.
+	Expected  int                 // Number of events the publisher produced, 0 when unknown.
+	Delivered int                 // Number of deliveries, duplicates included.
+	Unique    int                 // Number of distinct events delivered.
+	Corrupted int                 // Deliveries whose checksum did not match.
+	Doomed    []DoomedDelivery    // Deliveries of rolled back events; any entry is an outbox violation.
+}
+
+// Unaccounted returns the number of expected events that were never delivered,
+// including those lost after the highest delivered sequence number of their key.
+func (r DeliveryReport) Unaccounted() int {
+	return max(0, r.Expected-r.Unique)
+}
+
+// OK reports whether every expected event was delivered exactly once and in order.
+func (r DeliveryReport) OK() bool {
+	for _, key := range r.Keys {
+		if len(key.Missing) > 0 || len(key.Duplicates) > 0 || len(key.OutOfOrder) > 0 {
+			return false
+		}
+	}
+	return r.Unaccounted() == 0 && r.Corrupted == 0 && len(r.Doomed) == 0
+}
+
+// ForRuns returns the part of the report produced by the given publisher runs.
+// Corrupted deliveries cannot be attributed to a run and are kept as is; Expected is left for the caller to set.
+//
+// Parameters:
+//   - runIDs: The identifiers of the publisher runs to keep.
+//
+// Returns:
+//   - The report restricted to the keys and rolled back deliveries of the given runs.
+func (r DeliveryReport) ForRuns(runIDs []string) DeliveryReport {
+	runs := make(map[string]bool, len(runIDs))
+	for _, runID := range runIDs {
+		runs[runID] = true
+	}
+
+	filtered := DeliveryReport{Corrupted: r.Corrupted}
+	for _, key := range r.Keys {
+		if runs[key.RunID] {
+			filtered.Keys = append(filtered.Keys, key)
+			filtered.Delivered += key.Delivered
+			filtered.Unique += key.Unique
+		}
+	}
+	for _, doomed := range r.Doomed {
+		if runs[doomed.RunID] {
+			filtered.Doomed = append(filtered.Doomed, doomed)
+		}
+	}
+
+	return filtered
+}
+
+// NewDeliveryTracker creates a tracker.
+//
+// Parameters:
+//   - expected: The number of events the publisher produced, or 0 when unknown.
+//
+// Returns:
+//   - A new DeliveryTracker.
+func NewDeliveryTracker(expected int) *DeliveryTracker {
+	return &DeliveryTracker{
+		keys:     map[deliveryKey]*keyDeliveries{},
+		expected: expected,
+		done:     make(chan struct{}),
+	}
+}
+
+// Record records the delivery of an event.
+//
+// Parameters:
+//   - runID: The identifier of the publisher run that produced the event.
+//   - topic: The topic of the event.
+//   - orderingKey: The ordering key of the event.
+//   - sequence: The sequence number of the event within its run, topic and ordering key.
+func (t *DeliveryTracker) Record(runID, topic, orderingKey string, sequence int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Step 1: Find the deliveries of the run, topic and ordering key.
+	id := deliveryKey{runID: runID, topic: topic, orderingKey: orderingKey}
+	key, ok := t.keys[id]
+	if !ok {
+		key = &keyDeliveries{counts: map[int]int{}, highest: -1}
+		t.keys[id] = key
+	}
+
+	// Step 2: Classify the delivery.
+	key.delivered++
+	key.counts[sequence]++
+	switch {
+	case key.counts[sequence] > 1:
+		// Duplicates are reported from the counts.
+	case sequence < key.highest:
+		key.outOfOrder = append(key.outOfOrder, OutOfOrderDelivery{Sequence: sequence, After: key.highest})
+		t.unique++
+	default:
+		key.highest = sequence
+		t.unique++
+	}
+
+	// Step 3: Signal once every expected event was delivered.
+	if t.expected > 0 && t.unique >= t.expected {
+		t.doneOnce.Do(func() { close(t.done) })
+	}
+}
+
+// RecordCorrupted records the delivery of an event whose checksum did not match.
+func (t *DeliveryTracker) RecordCorrupted() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.corrupted++
+}
+
+// RecordDoomed records the delivery of an event whose transaction was deliberately rolled back.
+//
+// Parameters:
+//   - runID: The identifier of the publisher run that produced the event.
+//   - topic: The topic of the event.
+//   - orderingKey: The ordering key of the event.
+//   - sequence: The sequence number of the event in the doomed sequence space.
+func (t *DeliveryTracker) RecordDoomed(runID, topic, orderingKey string, sequence int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.doomed = append(t.doomed, DoomedDelivery{RunID: runID, Topic: topic, OrderingKey: orderingKey, Sequence: sequence})
+}
+
+// Done returns a channel closed once every expected event was delivered.
+// The channel is never closed when the expected number of events is unknown.
+func (t *DeliveryTracker) Done() <-chan struct{} {
+	return t.done
+}
+
+// Report summarizes the deliveries recorded so far.
+//
+// Returns:
+//   - The report, with one entry per publisher run, topic and ordering key sorted by run, topic and key.
+func (t *DeliveryTracker) Report() DeliveryReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := DeliveryReport{
+		Expected:  t.expected,
+		Unique:    t.unique,
+		Corrupted: t.corrupted,
+		Doomed:    append([]DoomedDelivery(nil), t.doomed...),
+	}
+	for id, key := range t.keys {
+		keyReport := KeyDeliveryReport{
+			RunID:       id.runID,
+			Topic:       id.topic,
+			OrderingKey: id.orderingKey,
+			Delivered:   key.delivered,
+			Unique:      len(key.counts),
+			Highest:     key.highest,
+			Duplicates:  map[int]int{},
+			OutOfOrder:  append([]OutOfOrderDelivery(nil), key.outOfOrder...),
+		}
+		for sequence := 0; sequence < key.highest; sequence++ {
+			if key.counts[sequence] == 0 {
+				keyReport.Missing = append(keyReport.Missing, sequence)
+			}
+		}
+		for sequence, count := range key.counts {
+			if count > 1 {
+				keyReport.Duplicates[sequence] = count - 1
+			}
+		}
+
+		report.Delivered += key.delivered
+		report.Keys = append(report.Keys, keyReport)
+	}
+	sort.Slice(report.Keys, func(i, j int) bool {
+		if report.Keys[i].RunID != report.Keys[j].RunID {
+			return report.Keys[i].RunID < report.Keys[j].RunID
+		}
+		if report.Keys[i].Topic != report.Keys[j].Topic {
+			return report.Keys[i].Topic < report.Keys[j].Topic
+		}
+		return report.Keys[i].OrderingKey < report.Keys[j].OrderingKey
+	})
+
+	return report
+}
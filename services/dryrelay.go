@@ -0,0 +1,86 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines the dry relay, which logs the outbox rows the cron relay would publish without touching them.
+package services
+
+import (
+	"context"
+	"outbox/debugger/config"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxLoggedPayload is the number of payload bytes logged per row by the dry relay.
+const maxLoggedPayload = 512
+
+// runDryRelay logs the rows the cron relay would publish until the context is cancelled.
+//
+// Parameters:
+//   - ctx: The context whose cancellation, usually on SIGINT/SIGTERM, stops the dry relay.
+//   - cfg: The runtime configuration providing the database and relay settings.
+//
+// Behavior:
+//   - Does not start the cron relay of the outbox library nor contact the broker.
+//   - Every relay.interval, reads with the read-only "inspect" query up to relay.batchSize PENDING or FAILED rows
+//     of the relay tables whose next retry time is due, oldest first.
+//   - Logs the table, id, topic, ordering key, retry count and payload of every row the first time it is due with
+//     a given row version, so rows left in place are not logged again at every interval.
+//   - Never writes to the outbox tables: the rows stay as they are for the real relay.
+//
+// Returns:
+//   - nil once the context is cancelled.
+//   - An error if the database cannot be opened.
+func runDryRelay(ctx context.Context, cfg *config.Config) error {
+	// Step 1: Connect to the outbox database.
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	filter := OutboxFilter{Statuses: retryableStatuses, Limit: cfg.Relay.BatchSize}
+	for index := 1; index <= cfg.Relay.TableCount; index++ {
+		filter.Tables = append(filter.Tables, index)
+	}
+	log.Warn().Msgf("[DryRelay] Logging up to %d due row(s) every %ds from %d table(s); the outbox rows are left untouched",
+		cfg.Relay.BatchSize, cfg.Relay.Interval, cfg.Relay.TableCount)
+
+	// Step 2: Log the due rows every interval until the context is cancelled.
+	logged := map[string]string{} // Row version last logged per row id.
+	ticker := time.NewTicker(time.Duration(cfg.Relay.Interval) * time.Second)
+	defer ticker.Stop()
+	started := time.Now()
+	for {
+		filter.NextRetryTo = time.Now()
+		rows, err := selectOutboxRows(ctx, db, filter.Tables, filter)
+		switch {
+		case ctx.Err() != nil: // Stopping, the query was cancelled.
+		case err != nil:
+			log.Error().Msgf("[DryRelay] Could not read the due rows: %v", err)
+		default:
+			for _, row := range rows {
+				if logged[row.EventOutboxID] == row.RowVersion {
+					continue
+				}
+				logged[row.EventOutboxID] = row.RowVersion
+
+				payload := row.EventMessage
+				if len(payload) > maxLoggedPayload {
+					payload = payload[:maxLoggedPayload]
+				}
+				log.Info().Msgf("[DryRelay] Would publish row %s of %s to topic %s (ordering key %q, status %s, retry %d, %d bytes): %s",
+					row.EventOutboxID, outboxTableName(row.TableIndex), row.EventTopic, row.EventKey, row.Status, row.RetryCount,
+					len(row.EventMessage), payload)
+			}
+			log.Info().Msgf("[DryRelay] %d row(s) due", len(rows))
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msgf("[DryRelay] Stopped after %s: %d distinct row(s) would have been published, none was changed",
+				time.Since(started).Round(time.Second), len(logged))
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines the structured debug event published by the publisher and verified by the listener.
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// DebugEvent is the payload of every event published by the Outbox Debugger.
+// It carries everything the listener needs to verify the delivery of the event.
+type DebugEvent struct {
+	RunID         string    `json:"runId"`         // Identifier of the publisher run that produced the event.
+	Topic         string    `json:"topic"`         // Topic the event was published to.
+	Sequence      int       `json:"sequence"`      // Sequence number of the event within its run, topic and ordering key, starting at 0.
+	OrderingKey   string    `json:"orderingKey"`   // Ordering key the event was published with.
+	ProducedAt    time.Time `json:"producedAt"`    // Time the publisher started the transaction of the event.
+	CommittedAt   time.Time `json:"committedAt"`   // Time the event was handed to the outbox, right before its transaction commits.
+	TransactionID int64     `json:"transactionId"` // PostgreSQL transaction id the event was added in.
+	Doomed        bool      `json:"doomed"`        // The transaction was deliberately rolled back; the event must never be delivered.
+	Checksum      string    `json:"checksum"`      // SHA-256 of the other fields.
+}
+
+// NewDebugEvent builds a debug event and computes its checksum.
+//
+// Parameters:
+//   - runID: The identifier of the publisher run.
+//   - topic: The topic the event is published to.
+//   - sequence: The sequence number of the event within its run, topic and ordering key.
+//   - orderingKey: The ordering key the event is published with.
+//   - transactionID: The PostgreSQL transaction id the event is added in.
+//   - producedAt: The time the transaction of the event started.
+//   - doomed: Whether the transaction will be deliberately rolled back.
+//
+// Behavior:
+//   - Stamps the event with the current time as commit time; call it right before adding the event to the outbox.
+//     The payload is written before the transaction commits, so this is the closest commit time it can carry.
+//
+// Returns:
+//   - The debug event.
+func NewDebugEvent(runID, topic string, sequence int, orderingKey string, transactionID int64, producedAt time.Time, doomed bool) DebugEvent {
+	event := DebugEvent{
+		RunID:         runID,
+		Topic:         topic,
+		Sequence:      sequence,
+		OrderingKey:   orderingKey,
+		ProducedAt:    producedAt.UTC(),
+		CommittedAt:   time.Now().UTC(),
+		TransactionID: transactionID,
+		Doomed:        doomed,
+	}
+	event.Checksum = event.computeChecksum()
+	return event
+}
+
+// Valid reports whether the checksum of the event matches its fields.
+func (e DebugEvent) Valid() bool {
+	return e.Checksum == e.computeChecksum()
+}
+
+// computeChecksum returns the hex-encoded SHA-256 of every field except the checksum.
+func (e DebugEvent) computeChecksum() string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%d|%s|%s|%s|%d|%t",
+		e.RunID, e.Topic, e.Sequence, e.OrderingKey, e.ProducedAt.UTC().Format(time.RFC3339Nano), e.CommittedAt.UTC().Format(time.RFC3339Nano), e.TransactionID, e.Doomed))
+	return hex.EncodeToString(sum[:])
+}
+
+// currentTransactionID returns the PostgreSQL id of the given transaction.
+func currentTransactionID(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var id int64
+	if err := tx.QueryRowContext(ctx, "SELECT txid_current()").Scan(&id); err != nil {
+		return 0, fmt.Errorf("read transaction id: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,103 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file marks the outbox rows that ran out of retries, so the cron relay stops picking them up.
+package services
+
+import (
+	"context"
+	"fmt"
+	"outbox/debugger/config"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// OutboxStatusExhausted is the status of the rows whose retry count reached relay.maxRetries.
+// The outbox library has no retry limit and does not know this status; its cron relay only picks up PENDING and
+// FAILED rows, so exhausted rows stay in place until requeued or purged.
+const OutboxStatusExhausted = "EXHAUSTED"
+
+// SweepExhaustedRows marks the retryable rows whose retry count reached relay.maxRetries as EXHAUSTED.
+//
+// Parameters:
+//   - ctx: The context for the statements.
+//   - cfg: The runtime configuration providing the database and relay settings.
+//
+// Behavior:
+//   - Selects the exhausted rows of every table handled by the relay.
+//   - Updates each row only if its row_version is unchanged, like the "requeue" command, so rows the cron relay
+//     touched in the meantime are left alone; they are swept next time if they are still exhausted.
+//   - Gives every updated row a new row_version and updated_time_utc.
+//
+// Returns:
+//   - The number of rows marked as exhausted per table index; nothing when relay.maxRetries is 0.
+//   - An error if a statement fails.
+func SweepExhaustedRows(ctx context.Context, cfg *config.Config) (map[int]int64, error) {
+	// Step 1: Skip the sweep when the retries are unlimited.
+	swept := map[int]int64{}
+	if cfg.Relay.MaxRetries <= 0 {
+		return swept, nil
+	}
+
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Step 2: Select the exhausted rows of every table.
+	filter := OutboxFilter{Statuses: retryableStatuses, MinRetryCount: &cfg.Relay.MaxRetries}
+	for index := 1; index <= cfg.Relay.TableCount; index++ {
+		filter.Tables = append(filter.Tables, index)
+	}
+	rows, err := selectOutboxRows(ctx, db, filter.Tables, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Mark each row under optimistic locking.
+	now := time.Now().UTC()
+	for _, row := range rows {
+		res, err := db.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s SET status = $1, updated_time_utc = $2, row_version = $3
+			WHERE event_outbox_id = $4 AND row_version = $5`, outboxTableName(row.TableIndex)),
+			OutboxStatusExhausted, now, uuid.NewString(), row.EventOutboxID, row.RowVersion)
+		if err != nil {
+			return swept, fmt.Errorf("sweep %s in %s: %w", row.EventOutboxID, outboxTableName(row.TableIndex), err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return swept, err
+		}
+		swept[row.TableIndex] += affected
+	}
+
+	return swept, nil
+}
+
+// sweepExhaustedPeriodically runs SweepExhaustedRows every relay.sweepInterval until the context is cancelled,
+// adding the number of exhausted rows to total. Failed sweeps are logged and retried at the next interval.
+func sweepExhaustedPeriodically(ctx context.Context, cfg *config.Config, total *atomic.Int64) {
+	ticker := time.NewTicker(time.Duration(cfg.Relay.SweepInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			swept, err := SweepExhaustedRows(ctx, cfg)
+			if err != nil {
+				log.Error().Msgf("[Relay] Sweep of exhausted rows failed: %v", err)
+				continue
+			}
+			for index, count := range swept {
+				total.Add(count)
+				if count > 0 {
+					log.Warn().Msgf("[Relay] %s: %d row(s) reached %d retries and were marked %s", outboxTableName(index), count, cfg.Relay.MaxRetries, OutboxStatusExhausted)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines the failures injected into the listener to provoke redeliveries.
+package services
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// ErrInjectedFailure is returned by the listener handler for the deliveries chosen by a FaultInjector.
+var ErrInjectedFailure = errors.New("injected listener failure")
+
+// FaultInjector makes a configurable fraction of the listener deliveries fail.
+// It is safe for concurrent use and its rate can be changed while the listener runs.
+type FaultInjector struct {
+	rate atomic.Uint64 // Failure rate, stored as the bits of a float64.
+}
+
+// SetFailureRate sets the fraction of deliveries that fail, between 0 (none) and 1 (all).
+func (f *FaultInjector) SetFailureRate(rate float64) {
+	f.rate.Store(math.Float64bits(rate))
+}
+
+// FailureRate returns the fraction of deliveries that fail.
+func (f *FaultInjector) FailureRate() float64 {
+	return math.Float64frombits(f.rate.Load())
+}
+
+// ShouldFail reports whether the current delivery must fail.
+// A nil injector never fails.
+func (f *FaultInjector) ShouldFail() bool {
+	if f == nil {
+		return false
+	}
+	rate := f.FailureRate()
+	return rate > 0 && rand.Float64() < rate
+}
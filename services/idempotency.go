@@ -0,0 +1,52 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file creates the idempotency layer skipping the messages the listener already processed.
+package services
+
+import (
+	"context"
+	"outbox/debugger/config"
+	"outbox/debugger/helper"
+
+	"github.com/rs/zerolog/log"
+)
+
+// NewListenerIdempotency creates the idempotency layer of the listener from listener.idempotency.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the idempotency, subscriber and database settings.
+//
+// Behavior:
+//   - The memory store keeps the last listener.idempotency.capacity keys of this process.
+//   - The postgres store records the keys in the listener_processed_message table under pubsub.subscriberName,
+//     so they survive restarts and are shared by the listeners of the subscription; its connection pool is
+//     closed by Close once the router stopped.
+//   - The key of a message is its UUID or, with the payload key, the SHA-256 of its payload.
+//
+// Returns:
+//   - The idempotency layer, to close once the router stopped, or nil with the none store.
+//   - An error if the outbox database cannot be reached.
+func NewListenerIdempotency(cfg *config.Config) (*helper.Idempotency, error) {
+	// Step 1: Select the key of the messages.
+	key := helper.MessageUUIDKey
+	if cfg.Listener.Idempotency.Key == config.IdempotencyKeyPayload {
+		key = helper.PayloadHashKey
+	}
+
+	// Step 2: Create the store of the processed keys.
+	var store helper.IdempotencyStore
+	switch cfg.Listener.Idempotency.Store {
+	case config.IdempotencyMemory:
+		store = helper.NewMemoryIdempotencyStore(cfg.Listener.Idempotency.Capacity)
+	case config.IdempotencyPostgres:
+		db, err := openOutboxDB(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		store = helper.NewSQLIdempotencyStore(db, cfg.PubSub.SubscriberName)
+	default:
+		return nil, nil
+	}
+
+	log.Info().Msgf("[OutboxDebugger] Skipping the messages already processed (%s store, %s key)", cfg.Listener.Idempotency.Store, cfg.Listener.Idempotency.Key)
+	return helper.NewIdempotency(store, key), nil
+}
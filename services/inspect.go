@@ -0,0 +1,296 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines the outbox row model, the row filter and the query used by the "inspect" command.
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"outbox/debugger/config"
+	"outbox/debugger/enum"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// OutboxRow is a single row of an event_outboxN table.
+type OutboxRow struct {
+	TableIndex       int       `json:"tableIndex"`       // Index of the table the row was read from.
+	EventOutboxID    string    `json:"eventOutboxId"`    // Primary key of the row.
+	EventGroup       string    `json:"eventGroup"`       // Group of the event.
+	EventTopic       string    `json:"eventTopic"`       // Topic the event is published to.
+	EventKey         string    `json:"eventKey"`         // Ordering key of the event.
+	EventMessage     []byte    `json:"-"`                // Raw event payload.
+	RetryCount       int       `json:"retryCount"`       // Number of publish attempts made by the relay.
+	LastRetryTimeUTC time.Time `json:"lastRetryTimeUtc"` // Time of the last publish attempt.
+	NextRetryTimeUTC time.Time `json:"nextRetryTimeUtc"` // Time of the next publish attempt.
+	Status           string    `json:"status"`           // Relay status of the row.
+	HashValue1       string    `json:"hashValue1"`       // Hash used to detect duplicate events.
+	CreatedTimeUTC   time.Time `json:"createdTimeUtc"`   // Creation time of the row.
+	UpdatedTimeUTC   time.Time `json:"updatedTimeUtc"`   // Last update time of the row.
+	RowVersion       string    `json:"rowVersion"`       // Optimistic locking version of the row.
+}
+
+// DecodedMessage returns the event payload in its most readable form.
+//
+// Returns:
+//   - A json.RawMessage if the payload is valid JSON.
+//   - A string if the payload is valid UTF-8 text.
+//   - A `\x`-prefixed hex string otherwise, like psql prints bytea values.
+func (r OutboxRow) DecodedMessage() any {
+	return decodePayload(r.EventMessage)
+}
+
+// decodePayload returns a raw payload as JSON, text or hex, whichever is the most readable.
+func decodePayload(payload []byte) any {
+	switch {
+	case json.Valid(payload):
+		return json.RawMessage(payload)
+	case utf8.Valid(payload):
+		return string(payload)
+	default:
+		return `\x` + hex.EncodeToString(payload)
+	}
+}
+
+// OutboxFilter selects rows across the outbox tables.
+// Zero values leave the corresponding criterion unset.
+type OutboxFilter struct {
+	Tables        []int     // Table indexes to query; empty means every table.
+	IDs           []string  // Accepted values of event_outbox_id.
+	Statuses      []string  // Accepted values of status.
+	Topics        []string  // Accepted values of event_topic.
+	EventKeys     []string  // Accepted values of event_key.
+	EventGroups   []string  // Accepted values of event_group.
+	MinRetryCount *int      // Inclusive lower bound of retry_count.
+	MaxRetryCount *int      // Inclusive upper bound of retry_count.
+	CreatedFrom   time.Time // Inclusive lower bound of created_time_utc.
+	CreatedTo     time.Time // Exclusive upper bound of created_time_utc.
+	NextRetryFrom time.Time // Inclusive lower bound of next_retry_time_utc.
+	NextRetryTo   time.Time // Exclusive upper bound of next_retry_time_utc.
+	Limit         int       // Maximum number of rows returned; 0 means unlimited.
+}
+
+// tableIndexes returns the table indexes selected by the filter.
+//
+// Error Handling:
+//   - Returns an error if an index is outside the tables created by the migration.
+func (f OutboxFilter) tableIndexes() ([]int, error) {
+	if len(f.Tables) == 0 {
+		all := make([]int, 0, enum.TableCount)
+		for i := 1; i <= enum.TableCount; i++ {
+			all = append(all, i)
+		}
+		return all, nil
+	}
+
+	for _, index := range f.Tables {
+		if index < 1 || index > enum.TableCount {
+			return nil, fmt.Errorf("table index %d must be between 1 and %d", index, enum.TableCount)
+		}
+	}
+	return f.Tables, nil
+}
+
+// where builds the parameterized WHERE clause of the filter.
+//
+// Returns:
+//   - The clause including the WHERE keyword, or an empty string if no criterion is set.
+//   - The positional arguments referenced by the clause.
+func (f OutboxFilter) where() (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if len(f.IDs) > 0 {
+		add("event_outbox_id = ANY($%d::uuid[])", pq.Array(f.IDs))
+	}
+	if len(f.Statuses) > 0 {
+		add("status = ANY($%d)", pq.Array(f.Statuses))
+	}
+	if len(f.Topics) > 0 {
+		add("event_topic = ANY($%d)", pq.Array(f.Topics))
+	}
+	if len(f.EventKeys) > 0 {
+		add("event_key = ANY($%d)", pq.Array(f.EventKeys))
+	}
+	if len(f.EventGroups) > 0 {
+		add("event_group = ANY($%d)", pq.Array(f.EventGroups))
+	}
+	if f.MinRetryCount != nil {
+		add("retry_count >= $%d", *f.MinRetryCount)
+	}
+	if f.MaxRetryCount != nil {
+		add("retry_count <= $%d", *f.MaxRetryCount)
+	}
+	if !f.CreatedFrom.IsZero() {
+		add("created_time_utc >= $%d", f.CreatedFrom.UTC())
+	}
+	if !f.CreatedTo.IsZero() {
+		add("created_time_utc < $%d", f.CreatedTo.UTC())
+	}
+	if !f.NextRetryFrom.IsZero() {
+		add("next_retry_time_utc >= $%d", f.NextRetryFrom.UTC())
+	}
+	if !f.NextRetryTo.IsZero() {
+		add("next_retry_time_utc < $%d", f.NextRetryTo.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// outboxRowColumns lists the columns scanned into an OutboxRow, in scan order.
+const outboxRowColumns = `event_outbox_id, event_group, event_topic, event_key, event_message, retry_count,
+	last_retry_time_utc, next_retry_time_utc, status, hash_value1, created_time_utc, updated_time_utc, row_version`
+
+// InspectOutbox lists the outbox rows matching the filter.
+//
+// Parameters:
+//   - ctx: The context for the queries.
+//   - cfg: The runtime configuration providing the database settings.
+//   - filter: The criteria selecting the tables and rows.
+//
+// Behavior:
+//   - Queries every selected event_outboxN table with the same criteria.
+//   - Merges the rows ordered by creation time and applies the limit.
+//
+// Returns:
+//   - The matching rows.
+//   - An error if the filter is invalid or a query fails.
+func InspectOutbox(ctx context.Context, cfg *config.Config, filter OutboxFilter) ([]OutboxRow, error) {
+	// Step 1: Resolve the tables to query.
+	indexes, err := filter.tableIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Connect to the outbox database.
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Step 3: Select the matching rows.
+	return selectOutboxRows(ctx, db, indexes, filter)
+}
+
+// CountOutbox counts the outbox rows matching the filter across the selected tables.
+//
+// Parameters:
+//   - ctx: The context for the queries.
+//   - cfg: The runtime configuration providing the database settings.
+//   - filter: The criteria selecting the tables and rows; the limit is ignored.
+//
+// Returns:
+//   - The number of matching rows.
+//   - An error if the filter is invalid or a query fails.
+func CountOutbox(ctx context.Context, cfg *config.Config, filter OutboxFilter) (int64, error) {
+	// Step 1: Resolve the tables to query.
+	indexes, err := filter.tableIndexes()
+	if err != nil {
+		return 0, err
+	}
+
+	// Step 2: Connect to the outbox database.
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	// Step 3: Count the matching rows of every table.
+	where, args := filter.where()
+	var total int64
+	for _, index := range indexes {
+		var count int64
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s%s", outboxTableName(index), where), args...).Scan(&count); err != nil {
+			return 0, fmt.Errorf("count %s: %w", outboxTableName(index), err)
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+// selectOutboxRows queries the given tables with the filter criteria.
+//
+// Parameters:
+//   - ctx: The context for the queries.
+//   - q: The database or transaction running the queries.
+//   - indexes: The table indexes to query.
+//   - filter: The criteria selecting the rows.
+//
+// Behavior:
+//   - Queries every table with the same criteria.
+//   - Merges the rows ordered by creation time and applies the limit.
+func selectOutboxRows(ctx context.Context, q queryer, indexes []int, filter OutboxFilter) ([]OutboxRow, error) {
+	// Step 1: Query each table with the same criteria.
+	where, args := filter.where()
+	var rows []OutboxRow
+	for _, index := range indexes {
+		query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY created_time_utc", outboxRowColumns, outboxTableName(index), where)
+		if filter.Limit > 0 {
+			query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+		}
+
+		tableRows, err := queryOutboxRows(ctx, q, index, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, tableRows...)
+	}
+
+	// Step 2: Merge the tables by creation time and apply the limit.
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].CreatedTimeUTC.Before(rows[j].CreatedTimeUTC)
+	})
+	if filter.Limit > 0 && len(rows) > filter.Limit {
+		rows = rows[:filter.Limit]
+	}
+
+	return rows, nil
+}
+
+// queryOutboxRows runs a query selecting outboxRowColumns and scans the result.
+//
+// Parameters:
+//   - ctx: The context for the query.
+//   - q: The database or transaction running the query.
+//   - index: The index of the queried table, recorded on every row.
+//   - query: The SQL query selecting outboxRowColumns.
+//   - args: The positional arguments of the query.
+func queryOutboxRows(ctx context.Context, q queryer, index int, query string, args ...any) ([]OutboxRow, error) {
+	result, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", outboxTableName(index), err)
+	}
+	defer result.Close()
+
+	var rows []OutboxRow
+	for result.Next() {
+		row := OutboxRow{TableIndex: index}
+		if err := result.Scan(
+			&row.EventOutboxID, &row.EventGroup, &row.EventTopic, &row.EventKey, &row.EventMessage, &row.RetryCount,
+			&row.LastRetryTimeUTC, &row.NextRetryTimeUTC, &row.Status, &row.HashValue1, &row.CreatedTimeUTC,
+			&row.UpdatedTimeUTC, &row.RowVersion,
+		); err != nil {
+			return nil, fmt.Errorf("scan %s: %w", outboxTableName(index), err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, result.Err()
+}
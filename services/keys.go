@@ -0,0 +1,143 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines how the publisher spreads its events over several ordering keys.
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+// KeyDistribution selects how the events of a PubOutboxDebugger run are spread over its ordering keys.
+type KeyDistribution string
+
+const (
+	KeyRoundRobin KeyDistribution = "roundRobin" // Cycle through the keys in order.
+	KeyUniform    KeyDistribution = "uniform"    // Pick every key with the same probability.
+	KeyZipf       KeyDistribution = "zipf"       // Pick the first keys far more often, like hot keys.
+)
+
+// DefaultZipfSkew is the skew of the zipf distribution when none is given; higher values make the first keys hotter.
+const DefaultZipfSkew = 1.1
+
+// ResolveOrderingKeys returns the ordering keys of a run from its single key, its key list or its generated keys.
+//
+// Parameters:
+//   - key: A single ordering key.
+//   - keys: A list of ordering keys.
+//   - count: The number of keys to generate, named prefix-0 to prefix-(count-1).
+//   - prefix: The prefix of the generated keys.
+//
+// Returns:
+//   - The ordering keys; none when neither is given, for events without an ordering key.
+//   - An error if more than one of key, keys and count is given or count is negative.
+func ResolveOrderingKeys(key string, keys []string, count int, prefix string) ([]string, error) {
+	// Step 1: Check that a single source of keys is given.
+	sources := 0
+	for _, set := range []bool{key != "", len(keys) > 0, count != 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, errors.New("orderingKey, orderingKeys and keyCount are mutually exclusive")
+	}
+	if count < 0 {
+		return nil, errors.New("keyCount must not be negative")
+	}
+
+	// Step 2: Build the keys from the given source.
+	switch {
+	case key != "":
+		return []string{key}, nil
+	case count > 0:
+		generated := make([]string, count)
+		for i := range generated {
+			generated[i] = fmt.Sprintf("%s-%d", prefix, i)
+		}
+		return generated, nil
+	default:
+		return keys, nil
+	}
+}
+
+// ValidateKeyDistribution checks that the distribution is known and its skew usable.
+//
+// Parameters:
+//   - distribution: The key distribution.
+//   - zipfSkew: The skew of the zipf distribution; 0 selects DefaultZipfSkew, otherwise it must be greater than 1.
+//
+// Returns:
+//   - nil if the distribution can be used.
+//   - An error describing the invalid setting otherwise.
+func ValidateKeyDistribution(distribution KeyDistribution, zipfSkew float64) error {
+	switch distribution {
+	case KeyRoundRobin, KeyUniform:
+		return nil
+	case KeyZipf:
+		if zipfSkew != 0 && zipfSkew <= 1 {
+			return fmt.Errorf("zipf skew must be greater than 1, got %g", zipfSkew)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown key distribution %q (use %s, %s or %s)", distribution, KeyRoundRobin, KeyUniform, KeyZipf)
+	}
+}
+
+// keyPicker picks the ordering key of every event of a run.
+// It is safe for concurrent use by the workers.
+type keyPicker struct {
+	mu           sync.Mutex      // Guards next, random and zipf.
+	keys         []string        // Ordering keys of the run; a single empty key when the events have none.
+	distribution KeyDistribution // How the keys are picked.
+	next         int             // Index of the next key in round-robin mode.
+	random       *rand.Rand      // Source of the uniform picks.
+	zipf         *rand.Zipf      // Source of the zipf picks.
+}
+
+// newKeyPicker creates a picker over the given keys.
+//
+// Parameters:
+//   - keys: The ordering keys; none means events without an ordering key.
+//   - distribution: How the keys are picked; round-robin when empty.
+//   - zipfSkew: The skew of the zipf distribution, DefaultZipfSkew when 0.
+//
+// Returns:
+//   - A new keyPicker.
+func newKeyPicker(keys []string, distribution KeyDistribution, zipfSkew float64) *keyPicker {
+	if len(keys) == 0 {
+		keys = []string{""}
+	}
+	if zipfSkew == 0 {
+		zipfSkew = DefaultZipfSkew
+	}
+
+	random := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	return &keyPicker{
+		keys:         keys,
+		distribution: distribution,
+		random:       random,
+		zipf:         rand.NewZipf(random, zipfSkew, 1, uint64(len(keys)-1)),
+	}
+}
+
+// Next returns the ordering key of the next event.
+func (p *keyPicker) Next() string {
+	if len(p.keys) == 1 {
+		return p.keys[0]
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.distribution {
+	case KeyUniform:
+		return p.keys[p.random.IntN(len(p.keys))]
+	case KeyZipf:
+		return p.keys[p.zipf.Uint64()]
+	default:
+		key := p.keys[p.next]
+		p.next = (p.next + 1) % len(p.keys)
+		return key
+	}
+}
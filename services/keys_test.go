@@ -0,0 +1,108 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResolveOrderingKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		keys    []string
+		count   int
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", want: nil},
+		{name: "single key", key: "order-1", want: []string{"order-1"}},
+		{name: "key list", keys: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "generated", count: 3, want: []string{"key-0", "key-1", "key-2"}},
+		{name: "key and list", key: "a", keys: []string{"b"}, wantErr: true},
+		{name: "key and count", key: "a", count: 2, wantErr: true},
+		{name: "list and count", keys: []string{"a"}, count: 2, wantErr: true},
+		{name: "negative count", count: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveOrderingKeys(tt.key, tt.keys, tt.count, "key")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("keys = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateKeyDistribution(t *testing.T) {
+	tests := []struct {
+		distribution KeyDistribution
+		zipfSkew     float64
+		wantErr      bool
+	}{
+		{KeyRoundRobin, 0, false},
+		{KeyUniform, 0, false},
+		{KeyZipf, 0, false},
+		{KeyZipf, 1.5, false},
+		{KeyZipf, 1, true},
+		{"hot", 0, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateKeyDistribution(tt.distribution, tt.zipfSkew); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateKeyDistribution(%s, %g) = %v, wantErr %v", tt.distribution, tt.zipfSkew, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKeyPicker(t *testing.T) {
+	keys := []string{"a", "b", "c", "d"}
+
+	t.Run("no keys", func(t *testing.T) {
+		picker := newKeyPicker(nil, KeyUniform, 0)
+		for range 3 {
+			if key := picker.Next(); key != "" {
+				t.Fatalf("Next() = %q, want the empty key", key)
+			}
+		}
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		picker := newKeyPicker(keys, KeyRoundRobin, 0)
+		for i := range 2 * len(keys) {
+			if key := picker.Next(); key != keys[i%len(keys)] {
+				t.Fatalf("pick %d = %q, want %q", i, key, keys[i%len(keys)])
+			}
+		}
+	})
+
+	counts := func(distribution KeyDistribution, zipfSkew float64, picks int) map[string]int {
+		picker := newKeyPicker(keys, distribution, zipfSkew)
+		counts := map[string]int{}
+		for range picks {
+			key := picker.Next()
+			if !slices.Contains(keys, key) {
+				t.Fatalf("Next() = %q, not one of %q", key, keys)
+			}
+			counts[key]++
+		}
+		return counts
+	}
+
+	t.Run("uniform", func(t *testing.T) {
+		for _, key := range keys {
+			if count := counts(KeyUniform, 0, 4000)[key]; count < 800 || count > 1200 {
+				t.Errorf("key %q picked %d times out of 4000, want about 1000", key, count)
+			}
+		}
+	})
+
+	t.Run("zipf", func(t *testing.T) {
+		picked := counts(KeyZipf, 2, 4000)
+		if picked["a"] <= picked["b"] || picked["b"] <= picked["d"] {
+			t.Errorf("zipf picks %v, want the first keys hotter", picked)
+		}
+	})
+}
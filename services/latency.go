@@ -0,0 +1,128 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file measures how long events spend between their transaction commit, their publication and their delivery.
+package services
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyRecorder collects the commit→publish and commit→receive latencies of the delivered events.
+// It is safe for concurrent use by the router handlers.
+type LatencyRecorder struct {
+	mu     sync.Mutex                 // Guards topics and keys.
+	topics map[string]*latencySamples // Samples per topic.
+	keys   map[string]*latencySamples // Samples per ordering key.
+}
+
+// latencySamples holds the latencies measured for one topic or ordering key.
+type latencySamples struct {
+	commitToPublish []time.Duration // Time from commit to the publication by the publisher or the relay.
+	commitToReceive []time.Duration // Time from commit to the delivery to the listener.
+}
+
+// LatencyStats summarizes a latency distribution.
+type LatencyStats struct {
+	Count int           // Number of samples.
+	P50   time.Duration // Median.
+	P90   time.Duration // 90th percentile.
+	P99   time.Duration // 99th percentile.
+	Max   time.Duration // Largest sample.
+}
+
+// LatencyGroup summarizes the latencies of one topic or ordering key.
+type LatencyGroup struct {
+	Name            string       // Topic or ordering key.
+	CommitToPublish LatencyStats // Latency from commit to publication.
+	CommitToReceive LatencyStats // Latency from commit to delivery.
+}
+
+// LatencyReport summarizes the latencies per topic and per ordering key.
+type LatencyReport struct {
+	Topics []LatencyGroup // Latencies per topic, sorted by name.
+	Keys   []LatencyGroup // Latencies per ordering key, sorted by name.
+}
+
+// NewLatencyRecorder creates an empty recorder.
+func NewLatencyRecorder() *LatencyRecorder {
+	return &LatencyRecorder{
+		topics: map[string]*latencySamples{},
+		keys:   map[string]*latencySamples{},
+	}
+}
+
+// Record records the latencies of a delivered event.
+//
+// Parameters:
+//   - topic: The topic the event was delivered on.
+//   - orderingKey: The ordering key of the event.
+//   - committedAt: The commit time carried by the event.
+//   - publishedAt: The publish time stamped by the publisher, or the zero time when unknown.
+//   - receivedAt: The time the listener received the event.
+func (r *LatencyRecorder) Record(topic, orderingKey string, committedAt, publishedAt, receivedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, samples := range []*latencySamples{samplesOf(r.topics, topic), samplesOf(r.keys, orderingKey)} {
+		if !publishedAt.IsZero() {
+			samples.commitToPublish = append(samples.commitToPublish, publishedAt.Sub(committedAt))
+		}
+		samples.commitToReceive = append(samples.commitToReceive, receivedAt.Sub(committedAt))
+	}
+}
+
+// Report summarizes the latencies recorded so far.
+func (r *LatencyRecorder) Report() LatencyReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return LatencyReport{Topics: latencyGroups(r.topics), Keys: latencyGroups(r.keys)}
+}
+
+// samplesOf returns the samples of the given name, creating them if needed.
+func samplesOf(groups map[string]*latencySamples, name string) *latencySamples {
+	samples, ok := groups[name]
+	if !ok {
+		samples = &latencySamples{}
+		groups[name] = samples
+	}
+	return samples
+}
+
+// latencyGroups summarizes every group, sorted by name.
+func latencyGroups(groups map[string]*latencySamples) []LatencyGroup {
+	result := make([]LatencyGroup, 0, len(groups))
+	for name, samples := range groups {
+		result = append(result, LatencyGroup{
+			Name:            name,
+			CommitToPublish: latencyStats(samples.commitToPublish),
+			CommitToReceive: latencyStats(samples.commitToReceive),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// latencyStats computes the percentiles of the samples using the nearest-rank method.
+func latencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	percentile := func(p int) time.Duration {
+		rank := (p*len(sorted) + 99) / 100
+		return sorted[max(rank, 1)-1]
+	}
+
+	return LatencyStats{
+		Count: len(sorted),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
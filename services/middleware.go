@@ -0,0 +1,146 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file builds the retry, timeout and throttle middleware of the listener handlers and their error rules.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"outbox/debugger/config"
+	"outbox/debugger/helper"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// handlerMiddleware builds the middleware of the handler of a topic, outermost first.
+//
+// Parameters:
+//   - topic: The topic of the handler, logged with every retry.
+//   - settings: The middleware settings of the handler, see config.HandlerMiddleware.
+//   - deadLetter: The middleware forwarding the poison messages, nil if disabled.
+//   - logger: The Watermill logger used by the retry middleware.
+//
+// Behavior:
+//   - Throttles the messages entering the handler, so the retries of a message count once.
+//   - Forwards the poison messages to the dead-letter target without retrying them.
+//   - Retries the other failures in process with exponential backoff and jitter; a message still failing
+//     afterwards is nacked, so the broker redelivers it.
+//   - Bounds every processing attempt by the timeout, set as deadline of the message context.
+//
+// Returns:
+//   - The middleware to add to the handler, possibly none.
+func handlerMiddleware(topic string, settings config.ListenerMiddlewareConfig, deadLetter message.HandlerMiddleware, logger watermill.LoggerAdapter) []message.HandlerMiddleware {
+	var middlewares []message.HandlerMiddleware
+
+	// Step 1: Throttle the incoming messages.
+	if settings.Throttle > 0 {
+		middlewares = append(middlewares, middleware.NewThrottle(int64(settings.Throttle), time.Second).Middleware)
+	}
+
+	// Step 2: Forward the poison messages, which no retry can fix.
+	if deadLetter != nil {
+		middlewares = append(middlewares, deadLetter)
+	}
+
+	// Step 3: Retry the other failures.
+	if settings.Retry.MaxRetries > 0 {
+		middlewares = append(middlewares, retryMiddleware(topic, settings.Retry, logger))
+	}
+
+	// Step 4: Bound every attempt.
+	if settings.Timeout > 0 {
+		middlewares = append(middlewares, attemptTimeout(time.Duration(settings.Timeout)*time.Millisecond))
+	}
+
+	log.Info().Msgf("[OutboxDebugger] Handler of topic %s: %d retries (%dms to %dms, x%g, jitter %g), timeout %dms, throttle %d/s",
+		topic, settings.Retry.MaxRetries, settings.Retry.InitialInterval, settings.Retry.MaxInterval, settings.Retry.Multiplier,
+		settings.Retry.Jitter, settings.Timeout, settings.Throttle)
+	return middlewares
+}
+
+// retryMiddleware wraps the Watermill retry middleware so that poison errors are returned at once instead of retried.
+func retryMiddleware(topic string, settings config.RetryConfig, logger watermill.LoggerAdapter) message.HandlerMiddleware {
+	retry := middleware.Retry{
+		MaxRetries:          settings.MaxRetries,
+		InitialInterval:     time.Duration(settings.InitialInterval) * time.Millisecond,
+		MaxInterval:         time.Duration(settings.MaxInterval) * time.Millisecond,
+		Multiplier:          settings.Multiplier,
+		RandomizationFactor: settings.Jitter,
+		Logger:              logger,
+	}
+
+	return func(h message.HandlerFunc) message.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			// Hide the poison error from the retry middleware, then return it
+			var poison error
+			attempt := retry
+			attempt.OnRetryHook = func(retryNum int, delay time.Duration) {
+				log.Warn().Msgf("[OutboxDebugger] Retry %d/%d of message %s on topic %s after %v", retryNum, settings.MaxRetries, msg.UUID, topic, delay)
+			}
+			produced, err := attempt.Middleware(func(msg *message.Message) ([]*message.Message, error) {
+				produced, err := h(msg)
+				if errors.Is(err, helper.ErrPoison) {
+					poison = err
+					return produced, nil
+				}
+				return produced, err
+			})(msg)
+			if poison != nil {
+				return produced, poison
+			}
+			return produced, err
+		}
+	}
+}
+
+// attemptTimeout sets a deadline on the message context for every call of the handler, restoring the
+// previous context afterwards so the next retry gets its own deadline.
+func attemptTimeout(timeout time.Duration) message.HandlerMiddleware {
+	return func(h message.HandlerFunc) message.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			parent := msg.Context()
+			ctx, cancel := context.WithTimeout(parent, timeout)
+			defer cancel()
+
+			msg.SetContext(ctx)
+			defer msg.SetContext(parent)
+			return h(msg)
+		}
+	}
+}
+
+// listenerErrorRules builds the rules classifying the errors of the listener handlers.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing listener.errorRules.
+//
+// Behavior:
+//   - Converts every rule of listener.errorRules, in order, into a helper.ErrorRule.
+//   - Appends the built-in rules, so the configured ones take precedence and the first match wins.
+//
+// Returns:
+//   - The rules to install with helper.SetErrorRules.
+//   - An error if a configured rule cannot be parsed.
+func listenerErrorRules(cfg *config.Config) ([]helper.ErrorRule, error) {
+	var rules []helper.ErrorRule
+
+	// Step 1: Convert the configured rules.
+	for i, raw := range cfg.Listener.ErrorRules {
+		parsed, err := config.ParseErrorRule(raw)
+		if err != nil {
+			return nil, fmt.Errorf("listener.errorRules[%d]: %w", i, err)
+		}
+		match := helper.MatchMessage(parsed.Value)
+		if parsed.Match == config.ErrorMatchPostgresCode {
+			match = helper.MatchPostgresCode(parsed.Value)
+		}
+		rules = append(rules, helper.ErrorRule{Name: raw, Class: helper.ErrorClass(parsed.Class), Match: match})
+	}
+
+	// Step 2: Fall back to the built-in rules.
+	return append(rules, helper.DefaultErrorRules()...), nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"outbox/debugger/config"
+	"outbox/debugger/helper"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestListenerErrorRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.Listener.ErrorRules = []string{
+		"retryable:postgresCode:23505",
+		"permanent:message:insufficient balance",
+	}
+	rules, err := listenerErrorRules(cfg)
+	if err != nil {
+		t.Fatalf("listenerErrorRules: %v", err)
+	}
+	if want := len(cfg.Listener.ErrorRules) + len(helper.DefaultErrorRules()); len(rules) != want {
+		t.Fatalf("got %d rules, want %d", len(rules), want)
+	}
+
+	helper.SetErrorRules(rules...)
+	t.Cleanup(func() { helper.SetErrorRules(helper.DefaultErrorRules()...) })
+	tests := []struct {
+		name      string
+		err       error
+		wantClass helper.ErrorClass
+		wantRule  string
+	}{
+		{"configured code overrides built-in", fmt.Errorf("insert: %w", &pq.Error{Code: "23505"}), helper.ClassRetryable, "retryable:postgresCode:23505"},
+		{"configured message", errors.New("debit: insufficient balance"), helper.ClassPermanent, "permanent:message:insufficient balance"},
+		{"built-in kept", sql.ErrNoRows, helper.ClassPermanent, "no rows"},
+		{"explicit wins", helper.Duplicate(errors.New("insufficient balance")), helper.ClassDuplicate, "explicit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, rule := helper.Classify(tt.err)
+			if class != tt.wantClass || rule != tt.wantRule {
+				t.Errorf("Classify(%v) = %s, %q; want %s, %q", tt.err, class, rule, tt.wantClass, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestListenerErrorRulesInvalid(t *testing.T) {
+	for _, raw := range []string{"permanent", "fatal:message:x", "permanent:regexp:x", "permanent:message:"} {
+		cfg := config.Default()
+		cfg.Listener.ErrorRules = []string{raw}
+		if _, err := listenerErrorRules(cfg); err == nil {
+			t.Errorf("listenerErrorRules accepted %q", raw)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file validates that the listener receives the events of every ordering key in strictly increasing sequence order.
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderValidator checks that the sequence numbers of every publisher run, topic and ordering key are delivered in strictly
+// increasing order, and records every inversion. It is safe for concurrent use by the router handlers.
+type OrderValidator struct {
+	mu         sync.Mutex                      // Guards every field below.
+	last       map[deliveryKey]OrderedDelivery // Delivery with the highest sequence number so far, per run, topic and key.
+	deliveries int                             // Number of deliveries checked.
+	inversions []OrderInversion                // Deliveries that did not increase the sequence of their key.
+}
+
+// OrderedDelivery identifies one delivery of an event.
+type OrderedDelivery struct {
+	Sequence    int       // Sequence number of the event.
+	MessageID   string    // Identifier of the broker message.
+	PublishedAt time.Time // Time the publisher or the relay published the message, zero when unknown.
+	ReceivedAt  time.Time // Time the listener received the message.
+}
+
+// OrderInversion describes a delivery whose sequence number was not greater than the highest one of its key.
+type OrderInversion struct {
+	RunID       string          // Identifier of the publisher run.
+	Topic       string          // Topic of the events.
+	OrderingKey string          // Ordering key of the events.
+	Late        OrderedDelivery // The delivery that arrived too late.
+	After       OrderedDelivery // The earlier delivery with the highest sequence number of the key.
+}
+
+// Redelivery reports whether the inversion is a repeated delivery of the same event rather than an older event.
+func (i OrderInversion) Redelivery() bool {
+	return i.Late.Sequence == i.After.Sequence
+}
+
+// OrderingReport summarizes the order of the deliveries seen by the listener.
+type OrderingReport struct {
+	Keys       int              // Number of publisher runs, topics and ordering keys seen.
+	Deliveries int              // Number of deliveries checked.
+	Inversions []OrderInversion // Inversions sorted by run, topic, ordering key and reception time.
+}
+
+// Preserved reports whether every ordering key was delivered in strictly increasing sequence order.
+func (r OrderingReport) Preserved() bool {
+	return len(r.Inversions) == 0
+}
+
+// ForRuns returns the inversions of the given publisher runs; Keys and Deliveries are kept as is.
+func (r OrderingReport) ForRuns(runIDs []string) OrderingReport {
+	runs := make(map[string]bool, len(runIDs))
+	for _, runID := range runIDs {
+		runs[runID] = true
+	}
+
+	filtered := OrderingReport{Keys: r.Keys, Deliveries: r.Deliveries}
+	for _, inversion := range r.Inversions {
+		if runs[inversion.RunID] {
+			filtered.Inversions = append(filtered.Inversions, inversion)
+		}
+	}
+
+	return filtered
+}
+
+// NewOrderValidator creates a validator.
+//
+// Returns:
+//   - A new OrderValidator.
+func NewOrderValidator() *OrderValidator {
+	return &OrderValidator{last: map[deliveryKey]OrderedDelivery{}}
+}
+
+// Record checks the delivery of an event against the previous deliveries of its key.
+//
+// Parameters:
+//   - runID: The identifier of the publisher run that produced the event.
+//   - topic: The topic of the event.
+//   - orderingKey: The ordering key of the event.
+//   - delivery: The sequence number, message id and timestamps of the delivery.
+//
+// Returns:
+//   - The inversion, if the sequence number is not greater than the highest one delivered for the key.
+//   - false if the delivery kept the order.
+func (v *OrderValidator) Record(runID, topic, orderingKey string, delivery OrderedDelivery) (OrderInversion, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Step 1: Accept the delivery if it increases the sequence of its key.
+	v.deliveries++
+	id := deliveryKey{runID: runID, topic: topic, orderingKey: orderingKey}
+	last, seen := v.last[id]
+	if !seen || delivery.Sequence > last.Sequence {
+		v.last[id] = delivery
+		return OrderInversion{}, false
+	}
+
+	// Step 2: Record the inversion.
+	inversion := OrderInversion{RunID: runID, Topic: topic, OrderingKey: orderingKey, Late: delivery, After: last}
+	v.inversions = append(v.inversions, inversion)
+	return inversion, true
+}
+
+// Report summarizes the deliveries checked so far.
+//
+// Returns:
+//   - The report, with the inversions sorted by run, topic, ordering key and reception time.
+func (v *OrderValidator) Report() OrderingReport {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	report := OrderingReport{
+		Keys:       len(v.last),
+		Deliveries: v.deliveries,
+		Inversions: append([]OrderInversion(nil), v.inversions...),
+	}
+	sort.SliceStable(report.Inversions, func(i, j int) bool {
+		a, b := report.Inversions[i], report.Inversions[j]
+		if a.RunID != b.RunID {
+			return a.RunID < b.RunID
+		}
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		if a.OrderingKey != b.OrderingKey {
+			return a.OrderingKey < b.OrderingKey
+		}
+		return a.Late.ReceivedAt.Before(b.Late.ReceivedAt)
+	})
+
+	return report
+}
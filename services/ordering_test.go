@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderValidatorRecord(t *testing.T) {
+	type delivery struct {
+		runID, topic, key string
+		sequence          int
+		wantInverted      bool
+		wantAfter         int
+	}
+	tests := []struct {
+		name       string
+		deliveries []delivery
+	}{
+		{"increasing", []delivery{
+			{"run", "topic", "a", 0, false, 0},
+			{"run", "topic", "a", 1, false, 0},
+			{"run", "topic", "a", 5, false, 0},
+		}},
+		{"older event after newer", []delivery{
+			{"run", "topic", "a", 0, false, 0},
+			{"run", "topic", "a", 2, false, 0},
+			{"run", "topic", "a", 1, true, 2},
+			{"run", "topic", "a", 3, false, 0},
+		}},
+		{"redelivery", []delivery{
+			{"run", "topic", "a", 0, false, 0},
+			{"run", "topic", "a", 0, true, 0},
+		}},
+		{"keys, topics and runs are independent", []delivery{
+			{"run", "topic", "a", 3, false, 0},
+			{"run", "topic", "b", 0, false, 0},
+			{"run", "other", "a", 0, false, 0},
+			{"other", "topic", "a", 0, false, 0},
+			{"run", "topic", "a", 2, true, 3},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewOrderValidator()
+			inversions := 0
+			received := time.Now()
+			for i, d := range tt.deliveries {
+				received = received.Add(time.Millisecond)
+				inversion, inverted := validator.Record(d.runID, d.topic, d.key, OrderedDelivery{Sequence: d.sequence, ReceivedAt: received})
+				if inverted != d.wantInverted {
+					t.Fatalf("delivery %d (sequence %d): inverted = %v, want %v", i, d.sequence, inverted, d.wantInverted)
+				}
+				if !inverted {
+					continue
+				}
+				inversions++
+				if inversion.Late.Sequence != d.sequence || inversion.After.Sequence != d.wantAfter {
+					t.Errorf("delivery %d: inversion %d after %d, want %d after %d", i, inversion.Late.Sequence, inversion.After.Sequence, d.sequence, d.wantAfter)
+				}
+				if inversion.Redelivery() != (d.sequence == d.wantAfter) {
+					t.Errorf("delivery %d: Redelivery() = %v", i, inversion.Redelivery())
+				}
+			}
+
+			report := validator.Report()
+			if report.Deliveries != len(tt.deliveries) || len(report.Inversions) != inversions || report.Preserved() != (inversions == 0) {
+				t.Errorf("report: %d deliveries, %d inversions, preserved %v; want %d, %d", report.Deliveries, len(report.Inversions), report.Preserved(), len(tt.deliveries), inversions)
+			}
+		})
+	}
+}
+
+func TestOrderingReportForRuns(t *testing.T) {
+	validator := NewOrderValidator()
+	for _, runID := range []string{"a", "b"} {
+		validator.Record(runID, "topic", "key", OrderedDelivery{Sequence: 1})
+		validator.Record(runID, "topic", "key", OrderedDelivery{Sequence: 0})
+	}
+
+	report := validator.Report().ForRuns([]string{"b"})
+	if len(report.Inversions) != 1 || report.Inversions[0].RunID != "b" {
+		t.Errorf("ForRuns(b) = %+v, want the inversion of run b only", report.Inversions)
+	}
+	if report.Keys != 2 || report.Deliveries != 4 {
+		t.Errorf("ForRuns(b) counts %d keys and %d deliveries, want 2 and 4", report.Keys, report.Deliveries)
+	}
+}
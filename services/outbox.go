@@ -4,9 +4,15 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"outbox/debugger/broker"
+	"outbox/debugger/config"
 	"outbox/debugger/enum"
 	"time"
 
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+
 	"clodeo.tech/public/go-universe/pkg/db/rdbms/sqldb"
 	"github.com/rs/zerolog/log"
 
@@ -14,28 +20,26 @@ import (
 	outboxModel "clodeo.tech/public/go-outbox/event_outbox/model"
 )
 
-// initEventOutboxManager initializes the EventOutboxManager and its database manager.
+// connectOutboxDB connects to the outbox database and creates its SQL database manager.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the database settings.
 //
 // Returns:
-//   - EventOutboxManager: The manager responsible for handling outbox events.
 //   - SqlDbManager: The SQL database manager used for outbox operations.
-//
-// Behavior:
-//   - Connects to the database using the configuration from the `enum` package.
-//   - Sets up the SQL database manager for the outbox pattern.
-//   - Configures the event outbox manager with topic settings defined in `enum`.
+//   - DB: The connection pool of the SQL database manager, to close once the manager is no longer used.
 //
 // Error Handling:
 //   - Logs a fatal error and exits the application if the database connection fails.
-func initEventOutboxManager() (outbox.EventOutboxManager, sqldb.SqlDbManager) {
+func connectOutboxDB(cfg *config.Config) (sqldb.SqlDbManager, *sql.DB) {
 	// Step 1: Establish a connection to the SQL database.
 	outboxSqldb, err := sqldb.Connect(context.Background(), sqldb.DBConfig{
 		Driver:                enum.DbDriver,
-		DSN:                   enum.DbDSN,
-		MaxOpenConnections:    enum.DbMaxOpenConnections,
-		MaxIdleConnections:    enum.DbMaxIdleConnections,
-		ConnectionMaxLifetime: enum.DbConnectionMaxLifetime,
-		Retry:                 enum.DbRetry,
+		DSN:                   cfg.Database.DSN,
+		MaxOpenConnections:    cfg.Database.MaxOpenConnections,
+		MaxIdleConnections:    cfg.Database.MaxIdleConnections,
+		ConnectionMaxLifetime: cfg.Database.ConnectionMaxLifetime,
+		Retry:                 cfg.Database.Retry,
 	})
 
 	if err != nil {
@@ -43,43 +47,156 @@ func initEventOutboxManager() (outbox.EventOutboxManager, sqldb.SqlDbManager) {
 	}
 
 	// Step 2: Create the SQL database manager for the outbox.
-	sqldbOutboxManager := sqldb.New(&sqldb.Opts{
+	return sqldb.New(&sqldb.Opts{
 		DB: outboxSqldb,
-	})
+	}), outboxSqldb
+}
 
-	// Step 3: Configure the topic settings for the event outbox.
-	eventTopicIndexes := []outboxModel.TopicConfig{
-		{
-			Topic:               enum.TopicName,
-			Index:               enum.TableIndex,
-			DeleteExistingOnAdd: enum.DeleteExistingOnAdd,
-		},
+// initEventOutboxManager initializes the EventOutboxManager on top of the given database manager.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the outbox and relay settings.
+//   - sqldbOutboxManager: The SQL database manager the EventOutboxManager runs its transactions with.
+//
+// Returns:
+//   - EventOutboxManager: The manager responsible for handling outbox events.
+//
+// Behavior:
+//   - Configures the event outbox manager with every outbox topic of cfg and the table it is mapped to,
+//     the number of tables of relay.tableCount and the option of relay.managerOption.
+func initEventOutboxManager(cfg *config.Config, sqldbOutboxManager sqldb.SqlDbManager) outbox.EventOutboxManager {
+	// Step 1: Configure the topic settings for the event outbox, mapping every topic to its table.
+	var eventTopicIndexes []outboxModel.TopicConfig
+	for _, topic := range cfg.OutboxTopics() {
+		eventTopicIndexes = append(eventTopicIndexes, outboxModel.TopicConfig{
+			Topic:               topic.Name,
+			Index:               topic.TableIndex,
+			DeleteExistingOnAdd: topic.DeleteExistingOnAdd,
+		})
 	}
 
-	// Step 4: Return the initialized EventOutboxManager and SqlDbManager.
-	return outbox.NewEventOutboxManager(sqldbOutboxManager, 5, eventTopicIndexes, false), sqldbOutboxManager
+	// Step 2: Return the initialized EventOutboxManager. The second argument of NewEventOutboxManager is the number
+	// of event_outboxN tables, formerly hard-coded to the 5 tables of the migration.
+	return outbox.NewEventOutboxManager(sqldbOutboxManager, cfg.Relay.TableCount, eventTopicIndexes, cfg.Relay.ManagerOption)
 }
 
-// StartCron starts the cron service for processing outbox events.
+// StartCron runs the cron service for processing outbox events until the context is cancelled.
+//
+// Parameters:
+//   - ctx: The context whose cancellation, usually on SIGINT/SIGTERM, shuts the relay down.
+//   - cfg: The runtime configuration used to initialize the outbox manager and the publisher.
+//   - dryRelay: Only log the rows the relay would publish, without running the relay, see runDryRelay.
 //
 // Behavior:
-//   - Initializes the EventOutboxManager using `initEventOutboxManager`.
-//   - Starts the cron service with a batch size of 100 and a duration of 60 seconds.
+//   - With dryRelay, runs runDryRelay until ctx is cancelled instead of everything below; the outbox rows are left untouched.
+//   - Creates the publisher of the configured broker, like the "publish" command; the publish time is stamped on
+//     every relayed message.
+//   - Initializes the EventOutboxManager using `initEventOutboxManager` on a database manager whose transactions can be
+//     stopped, and hands it the publisher.
+//   - Starts the cron service with the batch size and interval of the relay settings.
+//   - With relay.maxRetries set, marks the rows that reached it as EXHAUSTED every relay.sweepInterval.
+//   - Once ctx is cancelled, refuses the transactions of the next cron batches before they touch any row (the rows
+//     stay in the outbox, unchanged, for the next relay), waits up to 30 seconds for the batch in progress to publish
+//     its messages and record their outcome, checks that no row is changed by a relay afterwards, then closes the
+//     publisher and the database.
+//   - Logs a summary of the messages relayed per topic, the failures, the exhausted rows and the outcome of the check.
 //
-// Error Handling:
-//   - Logs errors encountered during the cron service initialization or runtime.
+// Returns:
+//   - nil once the relay shut down cleanly.
+//   - An error if the publisher cannot be created, or if the publisher or the database could not be closed.
+//   - With dryRelay, an error if the database cannot be opened.
 //
 // Usage:
 //
 //	Call this function to continuously process outbox events in a background cron job.
-func StartCron() {
-	// Step 1: Initialize the outbox manager.
-	outboxManager, _ := initEventOutboxManager()
+func StartCron(ctx context.Context, cfg *config.Config, dryRelay bool) error {
+	// Step 1: Only log the due rows in dry mode, without running the relay.
+	if dryRelay {
+		return runDryRelay(ctx, cfg)
+	}
+
+	// Step 2: Create the publisher the relay sends to.
+	publisher, err := newRelayTarget(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Initialize the outbox manager and start the cron service with the specified settings.
+	relay := startOutboxCron(cfg, publisher)
+
+	// Step 4: Run until the context is cancelled.
+	<-ctx.Done()
+	log.Info().Msg("[Relay] Shutting down, draining the publishes in flight")
 
-	outboxManager.Init(nil)
+	// Step 5: Stop the cron batches, release the publisher and the database, then log what was relayed.
+	summary, err := relay.shutdown()
+	logRelaySummary(summary)
+	return err
+}
+
+// StartOutboxRelay starts the cron relay in the background, publishing to the configured broker.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the database, broker and outbox settings.
+//
+// Behavior:
+//   - Creates the publisher of the configured broker and hands it to a new EventOutboxManager,
+//     stamping the publish time on every relayed message.
+//   - Starts the cron service and the exhausted rows sweep, then returns immediately; the relay runs until the process exits.
+//
+// Returns:
+//   - nil once the relay is started.
+//   - An error if the publisher cannot be created.
+func StartOutboxRelay(cfg *config.Config) error {
+	publisher, err := newRelayTarget(cfg)
+	if err != nil {
+		return err
+	}
+
+	startOutboxCron(cfg, publisher)
+	return nil
+}
+
+// newRelayTarget creates the publisher the cron relay sends to: the publisher of the configured broker,
+// stamping the publish time on every message.
+func newRelayTarget(cfg *config.Config) (message.Publisher, error) {
+	publisher, err := broker.NewPublisher(cfg, watermill.NewStdLogger(false, false))
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Msgf("[Relay] Relaying to the %s broker", cfg.Broker.Type)
+	return broker.WithPublishTimestamp(publisher), nil
+}
+
+// startOutboxCron initializes an EventOutboxManager with the given publisher and starts its cron service
+// with the batch size and interval of the relay settings, along with the exhausted rows sweep if relay.maxRetries is set.
+//
+// Returns:
+//   - The running relay, counting the messages sent through the publisher and ready to be shut down.
+func startOutboxCron(cfg *config.Config, publisher message.Publisher) *outboxRelay {
+	// Step 1: Initialize the outbox manager with the stoppable transactions and the counting publisher.
+	sqldbOutboxManager, db := connectOutboxDB(cfg)
+	relay := &outboxRelay{
+		db:           db,
+		transactions: &relayTransactions{SqlDbManager: sqldbOutboxManager},
+		publisher:    newRelayPublisher(publisher),
+		tableCount:   cfg.Relay.TableCount,
+		started:      time.Now(),
+	}
+	outboxManager := initEventOutboxManager(cfg, relay.transactions)
+	outboxManager.Init(relay.publisher)
+
+	// Step 2: Start the cron service.
+	log.Info().Msgf("[Relay] Relaying up to %d row(s) every %ds from %d table(s)", cfg.Relay.BatchSize, cfg.Relay.Interval, cfg.Relay.TableCount)
+	outboxManager.StartCron(cfg.Relay.BatchSize, time.Duration(cfg.Relay.Interval)*time.Second)
+
+	// Step 3: Start the exhausted rows sweep.
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	relay.stopSweep = stopSweep
+	if cfg.Relay.MaxRetries > 0 {
+		log.Info().Msgf("[Relay] Marking rows with %d retries or more as %s every %ds", cfg.Relay.MaxRetries, OutboxStatusExhausted, cfg.Relay.SweepInterval)
+		go sweepExhaustedPeriodically(sweepCtx, cfg, &relay.exhausted)
+	}
 
-	// Step 2: Start the cron service with the specified settings.
-	outboxManager.StartCron(100, time.Duration(60)*time.Second)
-	// Block the program from exiting.
-	select {}
+	return relay
 }
@@ -1,86 +1,495 @@
 // Package services provides service layer implementations for the Outbox Debugger application.
-// This file defines functions for publishing messages to Google Cloud Pub/Sub using the Outbox pattern.
+// This file defines functions for publishing messages to the configured broker using the Outbox pattern.
 package services
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"outbox/debugger/enum"
+	"math/rand/v2"
+	"outbox/debugger/broker"
+	"outbox/debugger/config"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	outbox "clodeo.tech/public/go-outbox/event_outbox"
 	"clodeo.tech/public/go-outbox/event_outbox/model"
 	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill-googlecloud/pkg/googlecloud"
-	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
 )
 
-// PubOutboxDebugger publishes messages to Google Cloud Pub/Sub using the Outbox pattern.
+// PublishOptions configures a PubOutboxDebugger run.
+type PublishOptions struct {
+	UseOutbox       bool            // Add the events to the outbox; if false only publish them directly.
+	Topics          []string        // Topics the events are spread over in turn; none means every outbox topic.
+	OrderingKeys    []string        // Ordering keys of the events; none means events without an ordering key.
+	KeyDistribution KeyDistribution // How the events are spread over the ordering keys.
+	ZipfSkew        float64         // Skew of the zipf key distribution, DefaultZipfSkew when 0.
+	MaxMsg          int             // Maximum number of transactions to run, 0 for no limit.
+	EventsPerTx     int             // Number of events added per transaction.
+	RollbackRate    float64         // Fraction of transactions deliberately rolled back after adding their events.
+	CallbackMode    CallbackMode    // When the AfterAddEvent callbacks run.
+	Workers         int             // Number of goroutines publishing concurrently.
+	SerializeKeys   bool            // Run the transactions sharing a topic and ordering key one at a time, so their events are numbered and published in commit order.
+	Rate            float64         // Maximum number of events per second across all workers, 0 for no limit.
+	Duration        time.Duration   // Maximum duration of the run, 0 for no limit.
+}
+
+// Validate checks that the options describe a bounded run the outbox accepts.
+// It is shared by the "publish" command and the publish phases of scenarios.
 //
 // Parameters:
-//   - orderingKey: The ordering key used for message ordering in Pub/Sub.
-//   - useOutbox: The use outbox if false only publish message no save on outbox db.
-//   - maxMsg: The maximum number of messages to publish.
+//   - cfg: The runtime configuration providing the outbox topics.
+//
+// Returns:
+//   - nil if the run can be started.
+//   - An error joining every invalid setting otherwise.
+func (o PublishOptions) Validate(cfg *config.Config) error {
+	var errs []error
+
+	// Step 1: Validate the bounds and the pace of the run.
+	if o.MaxMsg < 0 || o.Duration < 0 || (o.MaxMsg == 0 && o.Duration == 0) {
+		errs = append(errs, errors.New("maxMsg or duration must be greater than 0"))
+	}
+	if o.Workers <= 0 {
+		errs = append(errs, errors.New("workers must be greater than 0"))
+	}
+	if o.Rate < 0 {
+		errs = append(errs, errors.New("rate must not be negative"))
+	}
+
+	// Step 2: Validate the transactions.
+	if o.EventsPerTx <= 0 {
+		errs = append(errs, errors.New("eventsPerTx must be greater than 0"))
+	}
+	if o.RollbackRate < 0 || o.RollbackRate > 1 {
+		errs = append(errs, errors.New("rollbackRate must be between 0 and 1"))
+	}
+	switch o.CallbackMode {
+	case CallbackPerCommit, CallbackBatch:
+	case CallbackSkip:
+		if !o.UseOutbox {
+			errs = append(errs, errors.New("callbackMode skip requires useOutbox, otherwise nothing is published"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown callbackMode %q (use %s, %s or %s)", o.CallbackMode, CallbackPerCommit, CallbackBatch, CallbackSkip))
+	}
+
+	// Step 3: Validate the topics; the outbox rejects the events of a topic that is not mapped to a table.
+	registered := cfg.OutboxTopicNames()
+	for _, topic := range o.Topics {
+		switch {
+		case topic == "":
+			errs = append(errs, errors.New("topics must not contain an empty topic"))
+		case o.UseOutbox && !slices.Contains(registered, topic):
+			errs = append(errs, fmt.Errorf("topic %q is not an outbox topic (configured: %s)", topic, strings.Join(registered, ", ")))
+		}
+	}
+
+	// Step 4: Validate the key distribution.
+	if err := ValidateKeyDistribution(o.KeyDistribution, o.ZipfSkew); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// PublishResult reports the outcome of a PubOutboxDebugger run.
+type PublishResult struct {
+	RunID       string         // Identifier of the run, carried by every event.
+	Attempted   int            // Number of transactions started.
+	Committed   int            // Number of transactions committed.
+	RolledBack  int            // Number of transactions deliberately rolled back.
+	Failed      int            // Number of transactions that failed.
+	Events      int            // Number of events in committed transactions.
+	Callbacks   int            // Number of AfterAddEvent callbacks run.
+	Doomed      int            // Number of events in deliberately rolled back transactions.
+	Skipped     int            // Number of sequence numbers used up by failed transactions, reported missing by the listener; 0 with SerializeKeys.
+	KeyEvents   map[string]int // Number of events in committed transactions per ordering key.
+	TopicEvents map[string]int // Number of events in committed transactions per topic.
+	Errors      map[string]int // Number of failed transactions per error message.
+	Elapsed     time.Duration  // Duration of the run.
+	TxLatency   LatencyStats   // Duration of the transactions, from begin to commit or rollback.
+}
+
+// Throughput returns the number of committed transactions per second.
+func (r PublishResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Committed) / r.Elapsed.Seconds()
+}
+
+// EventsThroughput returns the number of committed events per second.
+func (r PublishResult) EventsThroughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Events) / r.Elapsed.Seconds()
+}
+
+// CallbackMode selects when the AfterAddEvent callbacks of a PubOutboxDebugger run are executed.
+// Callbacks of transactions that did not commit never run.
+type CallbackMode string
+
+const (
+	CallbackPerCommit CallbackMode = "perCommit" // Run the callbacks of a transaction right after it commits.
+	CallbackBatch     CallbackMode = "batch"     // Run the callbacks of every committed transaction once the run is over.
+	CallbackSkip      CallbackMode = "skip"      // Never run the callbacks, as if the publisher crashed after committing.
+)
+
+// errDoomedTransaction rolls back the transactions chosen by PublishOptions.RollbackRate.
+var errDoomedTransaction = errors.New("transaction deliberately rolled back")
+
+// publishProgressInterval is the interval between two progress logs of a run.
+const publishProgressInterval = 10 * time.Second
+
+// PubOutboxDebugger publishes messages to the configured broker using the Outbox pattern.
+//
+// Parameters:
+//   - ctx: The context stopping the run when cancelled.
+//   - cfg: The runtime configuration providing the database, broker and outbox settings.
+//   - opts: The number of events, the concurrency and the pace of the run.
 //
 // Behavior:
-//   - Initializes the EventOutboxManager and Google Cloud Pub/Sub publisher.
-//   - Publishes `maxMsg` number of messages using a transactional approach.
-//   - Executes callback functions after successfully adding events to the Outbox.
+//   - Initializes the EventOutboxManager and the publisher of the configured broker.
+//   - Starts opts.Workers goroutines, each adding opts.EventsPerTx DebugEvents per transaction, all with the same run id.
+//   - Spreads the events over opts.Topics in turn and over opts.OrderingKeys according to opts.KeyDistribution,
+//     numbering them per topic and key.
+//   - With opts.SerializeKeys, runs the transactions sharing a topic and ordering key one at a time and only uses up
+//     their sequence numbers once they commit, so the committed events of every topic and key are numbered without gaps,
+//     in commit order. Otherwise the transactions run fully concurrently: concurrent transactions of a key may commit and
+//     publish out of sequence order, and every failed transaction leaves a gap counted in Skipped.
+//   - Warns when the workers would gain nothing: serialized keys with a single topic and ordering key run one
+//     transaction at a time.
+//   - Rolls back a random opts.RollbackRate fraction of the transactions after their events were added; their events
+//     are marked as doomed and numbered in a separate sequence space, so the listener can flag any delivery of them.
+//   - Stops after opts.MaxMsg transactions, after opts.Duration or when ctx is cancelled, whichever comes first;
+//     the transactions in progress at that point are completed, and the callbacks of the committed ones still run.
+//   - Paces the transactions to opts.Rate events per second across all workers.
+//   - Executes the callback functions of committed transactions according to opts.CallbackMode: right after each
+//     commit, all at the end of the run in commit order, or never, leaving the delivery entirely to the cron relay.
+//     The callbacks of a transaction are run or queued before its topics and ordering keys are released, so the
+//     events of a key are published in sequence order.
+//   - Logs the progress every 10 seconds.
+//   - Closes the publisher and the database once the callbacks ran.
+//
+// Returns:
+//   - The throughput, transaction latency and errors of the run.
 //
 // Error Handling:
 //   - Logs and handles errors encountered during message publishing or transaction execution.
-func PubOutboxDebugger(useOutbox bool, orderingKey string, maxMsg int) {
+func PubOutboxDebugger(ctx context.Context, cfg *config.Config, opts PublishOptions) PublishResult {
 	// Step 1: Initialize the EventOutboxManager and SQL Database Manager.
-	outboxManager, sqlDbManager := initEventOutboxManager()
+	sqlDbManager, db := connectOutboxDB(cfg)
+	outboxManager := initEventOutboxManager(cfg, sqlDbManager)
 
-	// Step 2: Configure the Google Cloud Pub/Sub publisher.
-	pubSubConfig := googlecloud.PublisherConfig{
-		ProjectID:                 enum.ProjectId,
-		DoNotCreateTopicIfMissing: true,
-		EnableMessageOrdering:     true,
-		Marshaler: googlecloud.NewOrderingMarshaler(func(topic string, msg *message.Message) (string, error) {
-			return msg.Metadata.Get("ordering_key"), nil
-		}),
-	}
+	// Step 2: Configure the logger.
 	logger := watermill.NewStdLogger(false, false)
 
-	// Step 3: Create the Pub/Sub publisher.
-	publisher, err := googlecloud.NewPublisher(pubSubConfig, logger)
+	// Step 3: Create the publisher of the configured broker.
+	publisher, err := broker.NewPublisher(cfg, logger)
 	if err != nil {
 		log.Fatal().Msg(err.Error()) // Log and terminate if the publisher cannot be created.
 	}
 
-	// Step 4: Initialize the Outbox Manager with the publisher.
-	outboxManager.Init(publisher)
+	// Step 4: Initialize the Outbox Manager with the publisher, stamping the publish time on every message.
+	outboxManager.Init(broker.WithPublishTimestamp(publisher))
+
+	// Step 5: Bound the run by its duration and pace it.
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+	eventsPerTx := max(1, opts.EventsPerTx)
+	limiter := rate.NewLimiter(rate.Inf, eventsPerTx)
+	if opts.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.Rate), eventsPerTx)
+	}
 
-	// Step 5: Publish messages to the Outbox.
-	cbList := []model.AfterAddEventCallbackFunc{}
-	for i := 0; i < maxMsg; i++ {
-		// Wrap message publishing in a database transaction.
-		if err := sqlDbManager.WrapTransaction(context.Background(), func(ctx context.Context, tx *sql.Tx) error {
-			// Construct the event message.
-			msg := fmt.Sprintf("Event Message %d", i)
+	// Step 6: Publish messages to the Outbox from every worker. The run context only stops the loop; the transactions
+	// and their callbacks, possibly run after the run is over, use a context that is never cancelled.
+	txCtx := context.WithoutCancel(ctx)
+	run := &publishRun{
+		result:          PublishResult{RunID: uuid.NewString(), KeyEvents: map[string]int{}, TopicEvents: map[string]int{}, Errors: map[string]int{}},
+		sequences:       map[deliveryKey]int{},
+		doomedSequences: map[deliveryKey]int{},
+		keyLocks:        map[deliveryKey]*sync.Mutex{},
+		serialize:       opts.SerializeKeys,
+	}
+	topics := opts.Topics
+	if len(topics) == 0 {
+		topics = cfg.OutboxTopicNames()
+	}
+	topicPicker := newKeyPicker(topics, KeyRoundRobin, 0)
+	picker := newKeyPicker(opts.OrderingKeys, opts.KeyDistribution, opts.ZipfSkew)
+	log.Info().Msgf("Publishing run %s with %d worker(s) over %d topic(s) and %d ordering key(s)", run.result.RunID, max(1, opts.Workers), len(topics), len(picker.keys))
+	if opts.SerializeKeys && opts.Workers > 1 && len(topics)*len(picker.keys) == 1 {
+		log.Warn().Msgf("The %d workers share a single topic and ordering key with serialized keys, so they run one transaction at a time", opts.Workers)
+	}
+	var (
+		cbList []model.AfterAddEventCallbackFunc // Callbacks of the committed transactions in batch mode.
+		cbMu   sync.Mutex                        // Guards cbList.
+	)
+	var wg sync.WaitGroup
+	started := time.Now()
+	stopProgress := run.logProgress(started)
+	for w := 0; w < max(1, opts.Workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run.reserve(opts.MaxMsg) {
+				if err := limiter.WaitN(ctx, eventsPerTx); err != nil {
+					run.unreserve()
+					return // The run is over.
+				}
 
-			// Add the message to the Outbox and get the callback function.
-			cb, err := publishMessage(ctx, outboxManager, tx, useOutbox, orderingKey, msg)
-			if err != nil {
-				return err
-			}
+				// Pick the topic and ordering key of every event, then hold them until the transaction is over.
+				doomed := opts.RollbackRate > 0 && rand.Float64() < opts.RollbackRate
+				txKeys := make([]deliveryKey, eventsPerTx)
+				for e := range txKeys {
+					txKeys[e] = deliveryKey{topic: topicPicker.Next(), orderingKey: picker.Next()}
+				}
+				unlockKeys := run.lockKeys(txKeys)
 
-			// Append the callback function to the list.
-			cbList = append(cbList, cb)
+				// Wrap message publishing in a database transaction.
+				txStarted := time.Now()
+				var txCbList []model.AfterAddEventCallbackFunc
+				err := sqlDbManager.WrapTransaction(txCtx, func(ctx context.Context, tx *sql.Tx) error {
+					txCbList = txCbList[:0]
+					txID, err := currentTransactionID(ctx, tx)
+					if err != nil {
+						return err
+					}
 
-			return nil
-		}); err != nil {
-			log.Error().Msg(err.Error()) // Log errors during transaction execution.
-		}
+					sequences := run.sequencesOf(txKeys, doomed)
+					for e, key := range txKeys {
+						// Construct the event message.
+						msg := NewDebugEvent(run.result.RunID, key.topic, sequences[e], key.orderingKey, txID, txStarted, doomed)
+
+						// Add the message to the Outbox and get the callback function.
+						cb, err := publishMessage(ctx, outboxManager, tx, key.topic, opts.UseOutbox, key.orderingKey, msg)
+						if err != nil {
+							return err
+						}
+
+						// Keep the callback function until the transaction commits.
+						txCbList = append(txCbList, cb)
+					}
+
+					// Roll back the doomed transaction now that its events were added.
+					if doomed {
+						return errDoomedTransaction
+					}
+					return nil
+				})
+				run.finish(time.Since(txStarted), txKeys, err)
+				if err != nil {
+					unlockKeys()
+					if !errors.Is(err, errDoomedTransaction) {
+						log.Error().Msg(err.Error()) // Log errors during transaction execution.
+					}
+					continue // Callbacks of rolled back transactions never run.
+				}
+
+				// Run or keep the callback functions of the committed transaction before releasing its keys,
+				// so the next transaction of a key cannot publish its events first.
+				switch opts.CallbackMode {
+				case CallbackBatch:
+					cbMu.Lock()
+					cbList = append(cbList, txCbList...)
+					cbMu.Unlock()
+				case CallbackSkip:
+				default:
+					runCallbackFuncList(txCbList)
+					run.callbacksRun(len(txCbList))
+				}
+				unlockKeys()
+			}
+		}()
 	}
+	wg.Wait()
+	stopProgress()
+	run.result.Elapsed = time.Since(started)
 
-	// Step 6: Execute the callback functions if any were collected.
+	// Step 7: Execute the callback functions collected in batch mode.
 	if len(cbList) > 0 {
 		runCallbackFuncList(cbList)
+		run.callbacksRun(len(cbList))
+	}
+
+	// Step 8: Release the publisher and the database.
+	if err := publisher.Close(); err != nil {
+		log.Error().Msgf("Could not close the publisher: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		log.Error().Msgf("Could not close the outbox database: %v", err)
+	}
+
+	// Step 9: Summarize the run.
+	run.result.TxLatency = latencyStats(run.txLatencies)
+	return run.result
+}
+
+// publishRun holds the shared state of the workers of a PubOutboxDebugger run.
+type publishRun struct {
+	mu              sync.Mutex                  // Guards every field below.
+	result          PublishResult               // Counters of the run.
+	sequences       map[deliveryKey]int         // Next sequence number per topic and ordering key.
+	doomedSequences map[deliveryKey]int         // Next sequence number of doomed events per topic and ordering key.
+	keyLocks        map[deliveryKey]*sync.Mutex // Lock held by the transaction adding events of a topic and ordering key.
+	serialize       bool                        // Whether the transactions sharing a topic and ordering key run one at a time.
+	txLatencies     []time.Duration             // Duration of every finished transaction.
+}
+
+// reserve claims the next transaction of the run.
+// It returns false once maxMsg transactions were started; a maxMsg of 0 means no limit.
+func (r *publishRun) reserve(maxMsg int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if maxMsg > 0 && r.result.Attempted >= maxMsg {
+		return false
+	}
+	r.result.Attempted++
+	return true
+}
+
+// unreserve releases a transaction claimed by reserve that was never started.
+func (r *publishRun) unreserve() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Attempted--
+}
+
+// lockKeys locks the given topics and ordering keys, in a fixed order so two transactions cannot wait on each other,
+// and returns the function unlocking them. Transactions sharing a topic and ordering key thus run one at a time.
+// Nothing is locked unless the run serializes its keys.
+func (r *publishRun) lockKeys(keys []deliveryKey) (unlock func()) {
+	if !r.serialize {
+		return func() {}
+	}
+
+	// Step 1: Sort the distinct keys.
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, func(a, b deliveryKey) int {
+		return cmp.Or(cmp.Compare(a.topic, b.topic), cmp.Compare(a.orderingKey, b.orderingKey))
+	})
+	keys = slices.Compact(keys)
+
+	// Step 2: Lock them in order.
+	r.mu.Lock()
+	locks := make([]*sync.Mutex, 0, len(keys))
+	for _, key := range keys {
+		if r.keyLocks[key] == nil {
+			r.keyLocks[key] = &sync.Mutex{}
+		}
+		locks = append(locks, r.keyLocks[key])
+	}
+	r.mu.Unlock()
+	for _, lock := range locks {
+		lock.Lock()
 	}
+
+	return func() {
+		for _, lock := range slices.Backward(locks) {
+			lock.Unlock()
+		}
+	}
+}
+
+// sequencesOf returns the sequence numbers of events of the given topics and ordering keys.
+// With serialized keys, held with lockKeys, the numbers are only used up by finish once the transaction commits,
+// so a failed or retried transaction leaves no gap. Otherwise they are used up right away, so concurrent transactions
+// never share a number. Doomed events are numbered separately so rolled back transactions leave no gap in the committed sequence.
+func (r *publishRun) sequencesOf(keys []deliveryKey, doomed bool) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := r.sequences
+	if doomed {
+		next = r.doomedSequences
+	}
+	sequences := make([]int, len(keys))
+	taken := map[deliveryKey]int{}
+	for e, key := range keys {
+		sequences[e] = next[key] + taken[key]
+		taken[key]++
+	}
+	if !r.serialize {
+		for key, count := range taken {
+			next[key] += count
+		}
+	}
+	return sequences
+}
+
+// finish records the outcome of a transaction, given the topics and ordering keys of its events,
+// using up the sequence numbers of its events, with serialized keys, if it committed or was deliberately rolled back.
+func (r *publishRun) finish(latency time.Duration, keys []deliveryKey, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txLatencies = append(r.txLatencies, latency)
+	switch {
+	case errors.Is(err, errDoomedTransaction):
+		r.result.RolledBack++
+		r.result.Doomed += len(keys)
+		for _, key := range keys {
+			if r.serialize {
+				r.doomedSequences[key]++
+			}
+		}
+	case err != nil:
+		r.result.Failed++
+		r.result.Errors[err.Error()]++
+		if !r.serialize {
+			r.result.Skipped += len(keys)
+		}
+	default:
+		r.result.Committed++
+		r.result.Events += len(keys)
+		for _, key := range keys {
+			if r.serialize {
+				r.sequences[key]++
+			}
+			r.result.KeyEvents[key.orderingKey]++
+			r.result.TopicEvents[key.topic]++
+		}
+	}
+}
+
+// callbacksRun records the number of callbacks executed.
+func (r *publishRun) callbacksRun(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Callbacks += count
+}
+
+// logProgress logs the progress of the run every publishProgressInterval until the returned function is called.
+func (r *publishRun) logProgress(started time.Time) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(publishProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.mu.Lock()
+				committed, failed := r.result.Committed, r.result.Failed
+				r.mu.Unlock()
+				elapsed := time.Since(started)
+				log.Info().Msgf("[Publish] %s: %d committed, %d failed (%.1f tx/s)", elapsed.Truncate(time.Second), committed, failed, float64(committed)/elapsed.Seconds())
+			}
+		}
+	}()
+	return func() { close(done) }
 }
 
 // publishMessage publishes a single message to the Outbox.
@@ -89,6 +498,7 @@ func PubOutboxDebugger(useOutbox bool, orderingKey string, maxMsg int) {
 //   - ctx: The context for the operation.
 //   - outboxManager: The EventOutboxManager instance.
 //   - tx: The SQL transaction in which the message is published.
+//   - topic: The topic the message is published to.
 //   - useOutbox: The use outbox if false only publish message no save on outbox db.
 //   - orderingKey: The ordering key for the message.
 //   - eventMsg: The message payload to publish.
@@ -96,10 +506,10 @@ func PubOutboxDebugger(useOutbox bool, orderingKey string, maxMsg int) {
 // Returns:
 //   - cb: A callback function to execute after adding the event.
 //   - err: An error object if the operation fails.
-func publishMessage(ctx context.Context, outboxManager outbox.EventOutboxManager, tx *sql.Tx, useOutbox bool, orderingKey string, eventMsg any) (cb model.AfterAddEventCallbackFunc, err error) {
+func publishMessage(ctx context.Context, outboxManager outbox.EventOutboxManager, tx *sql.Tx, topic string, useOutbox bool, orderingKey string, eventMsg any) (cb model.AfterAddEventCallbackFunc, err error) {
 	// Step 1: Construct the AddEvent object.
 	msg := &model.AddEvent{
-		EventTopic:   enum.TopicName,
+		EventTopic:   topic,
 		EventKey:     orderingKey,
 		EventMessage: eventMsg,
 	}
@@ -0,0 +1,164 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file deletes or archives old outbox rows in small batches.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"outbox/debugger/config"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PurgeMode selects what happens to purged rows.
+type PurgeMode string
+
+const (
+	PurgeDelete  PurgeMode = "delete"  // Delete the rows.
+	PurgeArchive PurgeMode = "archive" // Move the rows to event_outbox_archive.
+)
+
+// PurgeOptions configures a purge.
+type PurgeOptions struct {
+	Filter    OutboxFilter  // Rows to purge; statuses and a creation time upper bound are required.
+	Mode      PurgeMode     // Delete or archive the rows.
+	BatchSize int           // Maximum number of rows removed per statement.
+	Sleep     time.Duration // Pause between two batches.
+	DryRun    bool          // Only count the rows that would be purged.
+}
+
+// PurgeResult reports the number of rows purged per table index.
+type PurgeResult map[int]int64
+
+// PurgeOutbox deletes or archives the outbox rows matching the options.
+//
+// Parameters:
+//   - ctx: The context stopping the purge between batches when cancelled.
+//   - cfg: The runtime configuration providing the database settings.
+//   - opts: The rows to purge and how to purge them.
+//
+// Behavior:
+//   - Processes each selected table in batches of at most BatchSize rows, each batch in its own statement.
+//   - Locks batch rows with FOR UPDATE SKIP LOCKED so rows being relayed by the cron are left alone; a batch
+//     may therefore purge fewer rows than BatchSize while more rows match, so a table is done once a batch purges none.
+//   - Logs the matching rows still left in a table, locked until the end of the purge.
+//   - In archive mode, deletes and inserts the batch into event_outbox_archive in the same statement.
+//   - Sleeps between batches so the cron keeps access to the tables.
+//
+// Returns:
+//   - The number of rows purged (or, in dry-run mode, matching) per table index.
+//   - An error if the options are invalid, a statement fails or the context is cancelled.
+func PurgeOutbox(ctx context.Context, cfg *config.Config, opts PurgeOptions) (PurgeResult, error) {
+	// Step 1: Validate the options.
+	if len(opts.Filter.Statuses) == 0 {
+		return nil, errors.New("purge requires at least one status")
+	}
+	if opts.Filter.CreatedTo.IsZero() {
+		return nil, errors.New("purge requires a retention window")
+	}
+	if opts.Mode != PurgeDelete && opts.Mode != PurgeArchive {
+		return nil, fmt.Errorf("unknown purge mode %q (use %s or %s)", opts.Mode, PurgeDelete, PurgeArchive)
+	}
+	if opts.BatchSize <= 0 {
+		return nil, errors.New("purge batch size must be greater than 0")
+	}
+	indexes, err := opts.Filter.tableIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Step 2: Purge every table batch by batch.
+	result := PurgeResult{}
+	where, args := opts.Filter.where()
+	for _, index := range indexes {
+		table := outboxTableName(index)
+
+		if opts.DryRun {
+			var count int64
+			if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s%s", table, where), args...).Scan(&count); err != nil {
+				return result, fmt.Errorf("count %s: %w", table, err)
+			}
+			result[index] = count
+			continue
+		}
+
+		query := purgeQuery(index, where, len(args), opts)
+		for {
+			batchArgs := slices.Clone(args)
+			if opts.Mode == PurgeArchive {
+				batchArgs = append(batchArgs, time.Now().UTC())
+			}
+
+			purged, err := purgeBatch(ctx, db, query, batchArgs)
+			if err != nil {
+				return result, fmt.Errorf("purge %s: %w", table, err)
+			}
+			result[index] += purged
+			log.Info().Msgf("[Purge] %s: %d row(s) %sd in batch, %d in total", table, purged, opts.Mode, result[index])
+
+			if purged == 0 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(opts.Sleep):
+			}
+		}
+
+		// Report the rows skipped because they stayed locked.
+		var left int64
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s%s", table, where), args...).Scan(&left); err != nil {
+			return result, fmt.Errorf("count %s: %w", table, err)
+		}
+		if left > 0 {
+			log.Warn().Msgf("[Purge] %s: %d matching row(s) left because they were locked, run the purge again to remove them", table, left)
+		}
+	}
+
+	return result, nil
+}
+
+// purgeQuery builds the statement purging one batch of a table.
+//
+// Parameters:
+//   - index: The index of the purged table.
+//   - where: The WHERE clause selecting the rows.
+//   - argCount: The number of arguments referenced by where; in archive mode the archive time follows them.
+//   - opts: The purge options providing the mode and batch size.
+func purgeQuery(index int, where string, argCount int, opts PurgeOptions) string {
+	table := outboxTableName(index)
+	batch := fmt.Sprintf(`WITH batch AS (
+			SELECT event_outbox_id FROM %s%s ORDER BY created_time_utc LIMIT %d FOR UPDATE SKIP LOCKED
+		)`, table, where, opts.BatchSize)
+
+	if opts.Mode == PurgeDelete {
+		return fmt.Sprintf(`%s
+		DELETE FROM %s AS t USING batch WHERE t.event_outbox_id = batch.event_outbox_id`, batch, table)
+	}
+
+	return fmt.Sprintf(`%s, moved AS (
+			DELETE FROM %s AS t USING batch WHERE t.event_outbox_id = batch.event_outbox_id RETURNING t.*
+		)
+		INSERT INTO event_outbox_archive (table_index, %s, archived_time_utc)
+		SELECT %d, %s, $%d FROM moved`, batch, table, outboxRowColumns, index, outboxRowColumns, argCount+1)
+}
+
+// purgeBatch runs one purge statement and returns the number of rows it affected.
+func purgeBatch(ctx context.Context, db *sql.DB, query string, args []any) (int64, error) {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
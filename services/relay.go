@@ -0,0 +1,268 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines the lifecycle of the cron relay: the publisher it relays to, its shutdown and its summary.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"clodeo.tech/public/go-universe/pkg/db/rdbms/sqldb"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/rs/zerolog/log"
+)
+
+// relayDrainTimeout is the maximum time the shutdown waits for the cron batch in progress, then for the publishes in flight.
+const relayDrainTimeout = 30 * time.Second
+
+// relaySettleTime is the time the shutdown watches the outbox tables after the drain, checking that no row changes.
+const relaySettleTime = 2 * time.Second
+
+// errRelayStopped rejects the transactions and publishes attempted by the cron relay after its shutdown started.
+// The rows stay PENDING or FAILED and are relayed by the next relay process.
+var errRelayStopped = errors.New("outbox relay is shutting down")
+
+// RelaySummary reports what a cron relay did while it was running.
+type RelaySummary struct {
+	Uptime               time.Duration  // Time between the start and the shutdown of the relay.
+	Published            int            // Number of messages published to the broker.
+	Failed               int            // Number of messages the broker rejected.
+	Rejected             int            // Number of messages refused because the relay was shutting down.
+	RefusedBatches       int            // Number of cron transactions refused because the relay was shutting down.
+	TopicPublished       map[string]int // Number of messages published per topic.
+	Exhausted            int64          // Number of rows marked EXHAUSTED by the sweep.
+	DrainedInFlight      bool           // Whether the cron batch and the publishes in flight completed before the publisher was closed.
+	ChangedAfterShutdown int64          // Number of rows a relay changed after the drain, -1 if the check failed.
+}
+
+// relayTransactions wraps the database manager handed to the outbox library, which runs the cron batches in its
+// transactions, so the shutdown can refuse the next batches and wait for the batch in progress, status updates included.
+type relayTransactions struct {
+	sqldb.SqlDbManager                // Wrapped database manager.
+	inFlight           sync.WaitGroup // Transactions in progress.
+
+	mu      sync.Mutex // Guards every field below.
+	stopped bool       // Whether the shutdown started.
+	refused int        // Number of transactions refused after the shutdown started.
+}
+
+// WrapTransaction runs fn in a transaction of the wrapped manager, unless the shutdown started.
+func (m *relayTransactions) WrapTransaction(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	m.mu.Lock()
+	if m.stopped {
+		m.refused++
+		m.mu.Unlock()
+		return errRelayStopped
+	}
+	m.inFlight.Add(1)
+	m.mu.Unlock()
+	defer m.inFlight.Done()
+
+	return m.SqlDbManager.WrapTransaction(ctx, fn)
+}
+
+// stop refuses new transactions and waits up to relayDrainTimeout for the ones in progress.
+// It reports whether they completed.
+func (m *relayTransactions) stop() bool {
+	m.mu.Lock()
+	m.stopped = true
+	m.mu.Unlock()
+
+	if waitTimeout(&m.inFlight, relayDrainTimeout) {
+		return true
+	}
+	log.Warn().Msgf("[Relay] Cron batch still running after %v, closing the publisher anyway", relayDrainTimeout)
+	return false
+}
+
+// waitTimeout waits for the wait group up to the timeout and reports whether it completed.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// relayPublisher wraps the publisher handed to the cron relay, counting the relayed messages
+// and letting the shutdown wait for the publishes in flight before the publisher is closed.
+type relayPublisher struct {
+	publisher message.Publisher // Wrapped publisher.
+	inFlight  sync.WaitGroup    // Publishes in progress.
+	closeOnce sync.Once         // Closes the wrapped publisher once.
+	closeErr  error             // Outcome of the first Close.
+
+	mu             sync.Mutex     // Guards every field below.
+	closed         bool           // Whether the shutdown started.
+	drained        bool           // Whether the publishes in flight completed before the close.
+	published      int            // Number of messages published.
+	failed         int            // Number of messages the wrapped publisher rejected.
+	rejected       int            // Number of messages refused after the shutdown started.
+	topicPublished map[string]int // Number of messages published per topic.
+}
+
+// newRelayPublisher wraps the publisher of the cron relay.
+func newRelayPublisher(publisher message.Publisher) *relayPublisher {
+	return &relayPublisher{publisher: publisher, topicPublished: map[string]int{}}
+}
+
+// Publish publishes the messages with the wrapped publisher, unless the shutdown started.
+func (p *relayPublisher) Publish(topic string, messages ...*message.Message) error {
+	// Step 1: Refuse the publish once the shutdown started, otherwise register it as in flight.
+	p.mu.Lock()
+	if p.closed {
+		p.rejected += len(messages)
+		p.mu.Unlock()
+		return errRelayStopped
+	}
+	p.inFlight.Add(1)
+	p.mu.Unlock()
+	defer p.inFlight.Done()
+
+	// Step 2: Publish and count the outcome.
+	err := p.publisher.Publish(topic, messages...)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failed += len(messages)
+	} else {
+		p.published += len(messages)
+		p.topicPublished[topic] += len(messages)
+	}
+	return err
+}
+
+// Close refuses new publishes, waits up to relayDrainTimeout for the publishes in flight, then closes the wrapped publisher.
+func (p *relayPublisher) Close() error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+
+		if waitTimeout(&p.inFlight, relayDrainTimeout) {
+			p.mu.Lock()
+			p.drained = true
+			p.mu.Unlock()
+		} else {
+			log.Warn().Msgf("[Relay] Publishes still in flight after %v, closing the publisher anyway", relayDrainTimeout)
+		}
+
+		p.closeErr = p.publisher.Close()
+	})
+	return p.closeErr
+}
+
+// outboxRelay is a running cron relay.
+type outboxRelay struct {
+	db           *sql.DB            // Connection pool of the outbox manager.
+	transactions *relayTransactions // Database manager the cron batches run with.
+	publisher    *relayPublisher    // Publisher the relay sends to.
+	tableCount   int                // Number of event_outboxN tables handled by the relay.
+	stopSweep    func()             // Stops the exhausted rows sweep.
+	exhausted    atomic.Int64       // Number of rows marked EXHAUSTED by the sweep.
+	started      time.Time          // Start of the relay.
+}
+
+// shutdown stops the sweep and the cron batches, drains and closes the publisher and closes the database.
+//
+// Behavior:
+//   - Refuses the transactions of the next cron batches and waits for the batch in progress, so no publish is refused
+//     half-way through a batch and no row is marked FAILED because of the shutdown.
+//   - Watches the outbox tables for relaySettleTime, counting the rows a relay changed since the drain.
+//
+// Returns:
+//   - The summary of the relay.
+//   - An error joining the failures to close the publisher and the database.
+func (r *outboxRelay) shutdown() (RelaySummary, error) {
+	var errs []error
+
+	// Step 1: Stop the sweep and the cron batches.
+	r.stopSweep()
+	batchDrained := r.transactions.stop()
+	drainedAt := time.Now().UTC()
+
+	// Step 2: Drain and close the publisher.
+	if err := r.publisher.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close relay publisher: %w", err))
+	}
+
+	// Step 3: Check that no row changes anymore, then close the database.
+	time.Sleep(relaySettleTime)
+	changed, err := changedRowsSince(r.db, r.tableCount, drainedAt)
+	if err != nil {
+		log.Error().Msgf("[Relay] Could not check the outbox rows after the shutdown: %v", err)
+		changed = -1
+	}
+	if err := r.db.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close outbox database: %w", err))
+	}
+
+	// Step 4: Summarize the relay.
+	r.transactions.mu.Lock()
+	refused := r.transactions.refused
+	r.transactions.mu.Unlock()
+	r.publisher.mu.Lock()
+	defer r.publisher.mu.Unlock()
+	summary := RelaySummary{
+		Uptime:               time.Since(r.started),
+		Published:            r.publisher.published,
+		Failed:               r.publisher.failed,
+		Rejected:             r.publisher.rejected,
+		RefusedBatches:       refused,
+		TopicPublished:       map[string]int{},
+		Exhausted:            r.exhausted.Load(),
+		DrainedInFlight:      batchDrained && r.publisher.drained,
+		ChangedAfterShutdown: changed,
+	}
+	for topic, count := range r.publisher.topicPublished {
+		summary.TopicPublished[topic] = count
+	}
+
+	return summary, errors.Join(errs...)
+}
+
+// changedRowsSince counts the outbox rows a relay updated since the given time: published or failed rows, as opposed
+// to the PENDING rows the publishers keep adding. Rows updated by another relay on the same tables are counted as well.
+func changedRowsSince(db *sql.DB, tableCount int, since time.Time) (int64, error) {
+	var total int64
+	for index := 1; index <= tableCount; index++ {
+		var count int64
+		err := db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE updated_time_utc >= $1 AND (status <> $2 OR retry_count > 0)`,
+			outboxTableName(index)), since, retryableStatuses[0]).Scan(&count)
+		if err != nil {
+			return 0, fmt.Errorf("check %s: %w", outboxTableName(index), err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// logRelaySummary logs the summary of a cron relay.
+func logRelaySummary(summary RelaySummary) {
+	topics := make([]string, 0, len(summary.TopicPublished))
+	for topic := range summary.TopicPublished {
+		topics = append(topics, fmt.Sprintf("%s=%d", topic, summary.TopicPublished[topic]))
+	}
+	sort.Strings(topics)
+
+	log.Info().Msgf("[Relay] Stopped after %s: %d message(s) published, %d failed, %d cron transaction(s) and %d message(s) refused during shutdown, %d row(s) marked %s, in-flight batch drained: %v",
+		summary.Uptime.Round(time.Second), summary.Published, summary.Failed, summary.RefusedBatches, summary.Rejected, summary.Exhausted, OutboxStatusExhausted, summary.DrainedInFlight)
+	if len(topics) > 0 {
+		log.Info().Msgf("[Relay] Published per topic: %s", strings.Join(topics, ", "))
+	}
+	if summary.ChangedAfterShutdown > 0 {
+		log.Warn().Msgf("[Relay] %d outbox row(s) were changed by a relay after the shutdown drained this one", summary.ChangedAfterShutdown)
+	}
+}
@@ -0,0 +1,104 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file resets stuck outbox rows so the cron relay picks them up again.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"outbox/debugger/config"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequeueChange describes the values written to every requeued row.
+type RequeueChange struct {
+	Status     string        // New value of status.
+	RetryCount int           // New value of retry_count.
+	Delay      time.Duration // Delay from now until next_retry_time_utc.
+}
+
+// RequeueResult reports the outcome of a requeue.
+type RequeueResult struct {
+	Selected         []OutboxRow // Rows matching the filter, as read before the update.
+	NextRetryTimeUTC time.Time   // Next retry time written to the requeued rows.
+	Requeued         int         // Number of rows updated.
+	Conflicts        []OutboxRow // Rows skipped because they changed after they were read.
+}
+
+// RequeueOutbox resets the status, retry count and next retry time of the rows matching the filter.
+//
+// Parameters:
+//   - ctx: The context for the queries.
+//   - cfg: The runtime configuration providing the database settings.
+//   - filter: The criteria selecting the rows; at least one criterion is required.
+//   - change: The values written to every selected row.
+//   - dryRun: Only select the rows and report what would change.
+//
+// Behavior:
+//   - Selects the matching rows across the selected tables.
+//   - Updates each row only if its row_version is unchanged, so rows touched by the cron relay
+//     in the meantime are reported as conflicts instead of being overwritten.
+//   - Gives every updated row a new row_version and updated_time_utc.
+//
+// Returns:
+//   - The selected rows and the outcome of the update.
+//   - An error if the filter or change is invalid or a query fails.
+func RequeueOutbox(ctx context.Context, cfg *config.Config, filter OutboxFilter, change RequeueChange, dryRun bool) (RequeueResult, error) {
+	// Step 1: Validate the request.
+	if where, _ := filter.where(); where == "" {
+		return RequeueResult{}, errors.New("requeue requires at least one row selection criterion")
+	}
+	if change.Status == "" {
+		return RequeueResult{}, errors.New("requeue requires the status to set")
+	}
+	if change.RetryCount < 0 {
+		return RequeueResult{}, errors.New("requeue retry count must not be negative")
+	}
+	indexes, err := filter.tableIndexes()
+	if err != nil {
+		return RequeueResult{}, err
+	}
+
+	// Step 2: Select the rows to requeue.
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return RequeueResult{}, err
+	}
+	defer db.Close()
+
+	rows, err := selectOutboxRows(ctx, db, indexes, filter)
+	if err != nil {
+		return RequeueResult{}, err
+	}
+	now := time.Now().UTC()
+	result := RequeueResult{Selected: rows, NextRetryTimeUTC: now.Add(change.Delay)}
+	if dryRun {
+		return result, nil
+	}
+
+	// Step 3: Update each row under optimistic locking.
+	for _, row := range rows {
+		res, err := db.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s
+			SET status = $1, retry_count = $2, next_retry_time_utc = $3, updated_time_utc = $4, row_version = $5
+			WHERE event_outbox_id = $6 AND row_version = $7`, outboxTableName(row.TableIndex)),
+			change.Status, change.RetryCount, result.NextRetryTimeUTC, now, uuid.NewString(), row.EventOutboxID, row.RowVersion)
+		if err != nil {
+			return result, fmt.Errorf("requeue %s in %s: %w", row.EventOutboxID, outboxTableName(row.TableIndex), err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		if affected == 0 {
+			result.Conflicts = append(result.Conflicts, row)
+			continue
+		}
+		result.Requeued++
+	}
+
+	return result, nil
+}
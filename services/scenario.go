@@ -0,0 +1,335 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file defines the scenario files describing scripted outbox experiments and the evaluation of their expectations.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"outbox/debugger/config"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cron actions of a scenario phase.
+const (
+	CronStart = "start" // Start the cron relay process.
+	CronStop  = "stop"  // Stop the cron relay process with SIGTERM.
+	CronKill  = "kill"  // Kill the cron relay process with SIGKILL.
+)
+
+// Default settings of a wait-for-drain phase.
+const (
+	defaultDrainTimeout  = 5 * time.Minute // Maximum time to wait for the drain.
+	defaultDrainInterval = 2 * time.Second // Interval between two drain checks.
+)
+
+// Scenario describes a scripted outbox experiment: phases run in order, then expectations checked on the results.
+type Scenario struct {
+	Name     string               `yaml:"name"`     // Name of the scenario, printed in the reports.
+	Broker   string               `yaml:"broker"`   // Broker overriding broker.type, empty to keep the configured one.
+	CronArgs []string             `yaml:"cronArgs"` // Extra arguments of the cron relay process.
+	Ordered  bool                 `yaml:"ordered"`  // Subscribe with message ordering and validate the order per key.
+	Phases   []ScenarioPhase      `yaml:"phases"`   // Phases run in order.
+	Expect   ScenarioExpectations `yaml:"expect"`   // Expectations checked once every phase ran.
+}
+
+// ScenarioPhase is one step of a scenario; exactly one of its actions must be set.
+type ScenarioPhase struct {
+	Name      string          `yaml:"name"`      // Name of the phase, printed in the logs.
+	Publish   *PublishPhase   `yaml:"publish"`   // Publish events and wait until the run is over.
+	Pause     time.Duration   `yaml:"pause"`     // Wait for the given duration.
+	Cron      string          `yaml:"cron"`      // Start, stop or kill the cron relay process.
+	Inject    *InjectPhase    `yaml:"inject"`    // Change the failures injected into the listener.
+	WaitDrain *WaitDrainPhase `yaml:"waitDrain"` // Wait until every committed event was delivered and relayed.
+}
+
+// PublishPhase configures the publisher run of a phase; its fields mirror the flags of the "publish" command.
+type PublishPhase struct {
+	UseOutbox       *bool           `yaml:"useOutbox"`       // Add the events to the outbox, true when omitted.
+	Topics          []string        `yaml:"topics"`          // Topics the events are spread over, every outbox topic when omitted.
+	OrderingKey     string          `yaml:"orderingKey"`     // Single ordering key of the events.
+	OrderingKeys    []string        `yaml:"orderingKeys"`    // Ordering keys of the events.
+	KeyCount        int             `yaml:"keyCount"`        // Number of ordering keys to generate, named keyPrefix-N.
+	KeyPrefix       string          `yaml:"keyPrefix"`       // Prefix of the generated ordering keys, "key" when omitted.
+	KeyDistribution KeyDistribution `yaml:"keyDistribution"` // How the events are spread over the keys, roundRobin when omitted.
+	ZipfSkew        float64         `yaml:"zipfSkew"`        // Skew of the zipf key distribution, DefaultZipfSkew when omitted.
+	MaxMsg          int             `yaml:"maxMsg"`          // Number of transactions to run, 0 for no limit.
+	EventsPerTx     int             `yaml:"eventsPerTx"`     // Number of events added per transaction.
+	RollbackRate    float64         `yaml:"rollbackRate"`    // Fraction of transactions deliberately rolled back.
+	CallbackMode    CallbackMode    `yaml:"callbackMode"`    // When the AfterAddEvent callbacks run.
+	Workers         int             `yaml:"workers"`         // Number of goroutines publishing concurrently.
+	SerializeKeys   bool            `yaml:"serializeKeys"`   // Run the transactions sharing a topic and ordering key one at a time.
+	Rate            float64         `yaml:"rate"`            // Maximum number of events per second, 0 for no limit.
+	Duration        time.Duration   `yaml:"duration"`        // Maximum duration of the run, 0 for no limit.
+}
+
+// InjectPhase configures the failures injected into the listener.
+type InjectPhase struct {
+	ListenerFailureRate float64 `yaml:"listenerFailureRate"` // Fraction of deliveries failed on purpose, 0 to stop injecting.
+}
+
+// WaitDrainPhase configures the wait until every committed event was delivered and no outbox row is left to relay.
+type WaitDrainPhase struct {
+	Timeout         time.Duration `yaml:"timeout"`         // Maximum time to wait, 5 minutes when omitted.
+	Interval        time.Duration `yaml:"interval"`        // Interval between two checks, 2 seconds when omitted.
+	PendingStatuses []string      `yaml:"pendingStatuses"` // Statuses of the outbox rows still to relay, PENDING and FAILED when omitted.
+}
+
+// ScenarioExpectations are the assertions checked once every phase ran; omitted expectations are not checked.
+// Listener expectations only count the events of the publisher runs of the scenario.
+type ScenarioExpectations struct {
+	AllDelivered       *bool                  `yaml:"allDelivered"`       // Every committed event was delivered at least once.
+	MaxMissing         *int                   `yaml:"maxMissing"`         // Maximum number of gaps in the delivered sequences.
+	MaxDuplicates      *int                   `yaml:"maxDuplicates"`      // Maximum number of extra deliveries.
+	MaxOutOfOrder      *int                   `yaml:"maxOutOfOrder"`      // Maximum number of deliveries after a later event of the same key.
+	MaxDoomedDelivered *int                   `yaml:"maxDoomedDelivered"` // Maximum number of deliveries of rolled back events.
+	MaxCorrupted       *int                   `yaml:"maxCorrupted"`       // Maximum number of deliveries with a checksum mismatch.
+	MaxOrderInversions *int                   `yaml:"maxOrderInversions"` // Maximum number of order inversions, redeliveries included; requires ordered.
+	Outbox             []OutboxRowExpectation `yaml:"outbox"`             // Bounds on the outbox rows created during the scenario.
+}
+
+// OutboxRowExpectation bounds the number of outbox rows created since the scenario started that match a filter.
+type OutboxRowExpectation struct {
+	Tables   []int    `yaml:"tables"`   // Table indexes to count; empty means every table.
+	Statuses []string `yaml:"statuses"` // Accepted values of status; empty means any.
+	Topics   []string `yaml:"topics"`   // Accepted values of event_topic; empty means any.
+	Min      *int     `yaml:"min"`      // Minimum number of rows.
+	Max      *int     `yaml:"max"`      // Maximum number of rows.
+}
+
+// LoadScenario reads and validates a YAML scenario file.
+//
+// Parameters:
+//   - path: The path of the scenario file.
+//   - cfg: The runtime configuration providing the outbox topics the publish phases may use.
+//
+// Returns:
+//   - The scenario, with the defaults of its phases applied.
+//   - An error if the file cannot be read, has unknown keys or describes an invalid scenario.
+func LoadScenario(path string, cfg *config.Config) (*Scenario, error) {
+	// Step 1: Decode the file, rejecting unknown keys.
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open scenario file: %w", err)
+	}
+	defer f.Close()
+
+	var scenario Scenario
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode scenario file %s: %w", path, err)
+	}
+
+	// Step 2: Apply the defaults and validate the scenario.
+	for i := range scenario.Phases {
+		scenario.Phases[i].applyDefaults()
+	}
+	if err := scenario.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+
+	return &scenario, nil
+}
+
+// Validate checks that the scenario can be run.
+//
+// Parameters:
+//   - cfg: The runtime configuration providing the outbox topics the publish phases may use.
+//
+// Returns:
+//   - nil if every phase and expectation is valid.
+//   - An error joining every invalid setting otherwise.
+func (s *Scenario) Validate(cfg *config.Config) error {
+	var errs []error
+
+	// Step 1: Validate the phases.
+	if len(s.Phases) == 0 {
+		errs = append(errs, errors.New("phases must not be empty"))
+	}
+	for i, phase := range s.Phases {
+		if err := phase.validate(cfg); err != nil {
+			errs = append(errs, fmt.Errorf("phase %d (%s): %w", i+1, phase.Name, err))
+		}
+	}
+
+	// Step 2: Validate the expectations.
+	if s.Expect.MaxOrderInversions != nil && !s.Ordered {
+		errs = append(errs, errors.New("expect.maxOrderInversions requires ordered"))
+	}
+	for i, expect := range s.Expect.Outbox {
+		if expect.Min == nil && expect.Max == nil {
+			errs = append(errs, fmt.Errorf("expect.outbox[%d]: min or max is required", i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// UsesCron reports whether a phase of the scenario starts the cron relay process.
+func (s *Scenario) UsesCron() bool {
+	for _, phase := range s.Phases {
+		if phase.Cron == CronStart {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks that the phase has exactly one valid action; publish phases are checked like the "publish" command.
+func (p ScenarioPhase) validate(cfg *config.Config) error {
+	// Step 1: Count the actions of the phase.
+	actions := 0
+	for _, set := range []bool{p.Publish != nil, p.Pause != 0, p.Cron != "", p.Inject != nil, p.WaitDrain != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return errors.New("exactly one of publish, pause, cron, inject and waitDrain must be set")
+	}
+
+	// Step 2: Validate the action.
+	switch {
+	case p.Publish != nil:
+		if _, err := ResolveOrderingKeys(p.Publish.OrderingKey, p.Publish.OrderingKeys, p.Publish.KeyCount, p.Publish.KeyPrefix); err != nil {
+			return fmt.Errorf("publish: %w", err)
+		}
+		if err := p.Publish.Options().Validate(cfg); err != nil {
+			return fmt.Errorf("publish: %w", err)
+		}
+	case p.Pause < 0:
+		return errors.New("pause must not be negative")
+	case p.Cron != "":
+		if p.Cron != CronStart && p.Cron != CronStop && p.Cron != CronKill {
+			return fmt.Errorf("cron %q is not one of %s, %s, %s", p.Cron, CronStart, CronStop, CronKill)
+		}
+	case p.Inject != nil:
+		if p.Inject.ListenerFailureRate < 0 || p.Inject.ListenerFailureRate >= 1 {
+			return errors.New("inject.listenerFailureRate must be in [0, 1)")
+		}
+	}
+
+	return nil
+}
+
+// applyDefaults fills in the omitted settings of the phase.
+func (p *ScenarioPhase) applyDefaults() {
+	if p.Publish != nil {
+		if p.Publish.UseOutbox == nil {
+			useOutbox := true
+			p.Publish.UseOutbox = &useOutbox
+		}
+		if p.Publish.CallbackMode == "" {
+			p.Publish.CallbackMode = CallbackPerCommit
+		}
+		if p.Publish.KeyPrefix == "" {
+			p.Publish.KeyPrefix = "key"
+		}
+		if p.Publish.KeyDistribution == "" {
+			p.Publish.KeyDistribution = KeyRoundRobin
+		}
+	}
+	if p.WaitDrain != nil {
+		if p.WaitDrain.Timeout <= 0 {
+			p.WaitDrain.Timeout = defaultDrainTimeout
+		}
+		if p.WaitDrain.Interval <= 0 {
+			p.WaitDrain.Interval = defaultDrainInterval
+		}
+		if len(p.WaitDrain.PendingStatuses) == 0 {
+			p.WaitDrain.PendingStatuses = slices.Clone(retryableStatuses)
+		}
+	}
+}
+
+// Options returns the options of the publisher run of the phase; the phase must have been validated.
+func (p *PublishPhase) Options() PublishOptions {
+	keys, _ := ResolveOrderingKeys(p.OrderingKey, p.OrderingKeys, p.KeyCount, p.KeyPrefix)
+	return PublishOptions{
+		UseOutbox:       *p.UseOutbox,
+		Topics:          p.Topics,
+		OrderingKeys:    keys,
+		KeyDistribution: p.KeyDistribution,
+		ZipfSkew:        p.ZipfSkew,
+		MaxMsg:          p.MaxMsg,
+		EventsPerTx:     max(1, p.EventsPerTx),
+		RollbackRate:    p.RollbackRate,
+		CallbackMode:    p.CallbackMode,
+		Workers:         max(1, p.Workers),
+		SerializeKeys:   p.SerializeKeys,
+		Rate:            p.Rate,
+		Duration:        p.Duration,
+	}
+}
+
+// Evaluate checks the expectations against the listener report and the outbox tables.
+//
+// Parameters:
+//   - ctx: The context for the outbox queries.
+//   - cfg: The runtime configuration providing the database settings.
+//   - report: The deliveries of the publisher runs of the scenario, with Expected set to their committed events.
+//   - ordering: The order inversions of the publisher runs of the scenario, nil when the order was not validated.
+//   - since: The start of the scenario; only the outbox rows created since then are counted.
+//
+// Returns:
+//   - One message per failed expectation, empty when every expectation holds.
+//   - An error if the outbox tables cannot be queried.
+func (e ScenarioExpectations) Evaluate(ctx context.Context, cfg *config.Config, report DeliveryReport, ordering *OrderingReport, since time.Time) ([]string, error) {
+	var failures []string
+
+	// Step 1: Check the listener expectations.
+	missing, duplicates, outOfOrder := 0, 0, 0
+	for _, key := range report.Keys {
+		missing += len(key.Missing)
+		outOfOrder += len(key.OutOfOrder)
+		for _, extra := range key.Duplicates {
+			duplicates += extra
+		}
+	}
+	if e.AllDelivered != nil && *e.AllDelivered && report.Unaccounted() > 0 {
+		failures = append(failures, fmt.Sprintf("allDelivered: %d of %d committed events were never delivered", report.Unaccounted(), report.Expected))
+	}
+	failures = appendBoundFailure(failures, "maxMissing", missing, e.MaxMissing)
+	failures = appendBoundFailure(failures, "maxDuplicates", duplicates, e.MaxDuplicates)
+	failures = appendBoundFailure(failures, "maxOutOfOrder", outOfOrder, e.MaxOutOfOrder)
+	failures = appendBoundFailure(failures, "maxDoomedDelivered", len(report.Doomed), e.MaxDoomedDelivered)
+	failures = appendBoundFailure(failures, "maxCorrupted", report.Corrupted, e.MaxCorrupted)
+	if ordering != nil {
+		failures = appendBoundFailure(failures, "maxOrderInversions", len(ordering.Inversions), e.MaxOrderInversions)
+	}
+
+	// Step 2: Check the outbox expectations.
+	for i, expect := range e.Outbox {
+		count, err := CountOutbox(ctx, cfg, OutboxFilter{
+			Tables:      expect.Tables,
+			Statuses:    expect.Statuses,
+			Topics:      expect.Topics,
+			CreatedFrom: since,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if expect.Min != nil && count < int64(*expect.Min) {
+			failures = append(failures, fmt.Sprintf("outbox[%d]: %d row(s) with status %v, expected at least %d", i, count, expect.Statuses, *expect.Min))
+		}
+		if expect.Max != nil && count > int64(*expect.Max) {
+			failures = append(failures, fmt.Sprintf("outbox[%d]: %d row(s) with status %v, expected at most %d", i, count, expect.Statuses, *expect.Max))
+		}
+	}
+
+	return failures, nil
+}
+
+// appendBoundFailure appends a failure message if the value exceeds the bound; a nil bound is not checked.
+func appendBoundFailure(failures []string, name string, value int, bound *int) []string {
+	if bound != nil && value > *bound {
+		failures = append(failures, fmt.Sprintf("%s: got %d, expected at most %d", name, value, *bound))
+	}
+	return failures
+}
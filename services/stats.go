@@ -0,0 +1,177 @@
+// Package services provides service layer implementations for the Outbox Debugger application.
+// This file aggregates the outbox tables into the health summary printed by the "stats" command.
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"outbox/debugger/config"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// retryableStatuses are the statuses of the rows the cron relay still tries to publish.
+var retryableStatuses = []string{"PENDING", "FAILED"}
+
+// OutboxGroupStats aggregates the rows of one table sharing the same topic and status.
+type OutboxGroupStats struct {
+	TableIndex    int           // Index of the aggregated table.
+	Topic         string        // Event topic of the rows.
+	Status        string        // Relay status of the rows.
+	Count         int64         // Number of rows.
+	Overdue       int64         // Number of PENDING or FAILED rows whose next retry time is in the past.
+	MaxRetryCount int           // Highest retry count of the rows.
+	OldestAge     time.Duration // Age of the oldest row.
+}
+
+// RetryBucket counts the rows sharing the same retry count.
+type RetryBucket struct {
+	RetryCount int   // Retry count of the rows.
+	Count      int64 // Number of rows.
+}
+
+// OutboxStats is a snapshot of the health of the outbox tables.
+type OutboxStats struct {
+	CollectedAt    time.Time          // Time the snapshot was taken.
+	Groups         []OutboxGroupStats // Aggregates per table, topic and status.
+	RetryHistogram []RetryBucket      // Row counts per retry count, ascending.
+	Total          int64              // Number of rows across every table.
+	Overdue        int64              // Number of overdue rows across every table.
+}
+
+// StatusTotals returns the number of rows per status across every table and topic.
+func (s OutboxStats) StatusTotals() map[string]int64 {
+	totals := map[string]int64{}
+	for _, group := range s.Groups {
+		totals[group.Status] += group.Count
+	}
+	return totals
+}
+
+// WatchOutboxStats collects outbox statistics once or periodically.
+//
+// Parameters:
+//   - ctx: The context stopping the collection when cancelled.
+//   - cfg: The runtime configuration providing the database settings.
+//   - tables: The table indexes to aggregate; empty means every table.
+//   - interval: The refresh interval; 0 collects a single snapshot.
+//   - report: The function receiving every snapshot.
+//
+// Returns:
+//   - nil once the single snapshot was reported or the context is cancelled.
+//   - An error if the tables are invalid or a query fails.
+func WatchOutboxStats(ctx context.Context, cfg *config.Config, tables []int, interval time.Duration, report func(OutboxStats)) error {
+	// Step 1: Resolve the tables and connect to the outbox database.
+	indexes, err := OutboxFilter{Tables: tables}.tableIndexes()
+	if err != nil {
+		return err
+	}
+	db, err := openOutboxDB(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Step 2: Collect and report snapshots until the context is cancelled.
+	ticker := time.NewTicker(max(interval, time.Second))
+	defer ticker.Stop()
+	for {
+		stats, err := collectOutboxStats(ctx, db, indexes)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		report(stats)
+
+		if interval <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectOutboxStats aggregates the given tables into a single snapshot.
+//
+// Parameters:
+//   - ctx: The context for the queries.
+//   - db: The outbox database.
+//   - indexes: The table indexes to aggregate.
+func collectOutboxStats(ctx context.Context, db *sql.DB, indexes []int) (OutboxStats, error) {
+	now := time.Now().UTC()
+	stats := OutboxStats{CollectedAt: now}
+	histogram := map[int]int64{}
+
+	for _, index := range indexes {
+		// Step 1: Aggregate the table per topic and status.
+		groups, err := db.QueryContext(ctx, fmt.Sprintf(`
+			SELECT event_topic, status, COUNT(*), COUNT(*) FILTER (WHERE next_retry_time_utc < $1 AND status = ANY($2)),
+				MAX(retry_count), MIN(created_time_utc)
+			FROM %s GROUP BY event_topic, status`, outboxTableName(index)), now, pq.Array(retryableStatuses))
+		if err != nil {
+			return OutboxStats{}, fmt.Errorf("aggregate %s: %w", outboxTableName(index), err)
+		}
+		for groups.Next() {
+			group := OutboxGroupStats{TableIndex: index}
+			var oldest time.Time
+			if err := groups.Scan(&group.Topic, &group.Status, &group.Count, &group.Overdue, &group.MaxRetryCount, &oldest); err != nil {
+				groups.Close()
+				return OutboxStats{}, fmt.Errorf("scan %s aggregate: %w", outboxTableName(index), err)
+			}
+			group.OldestAge = now.Sub(oldest)
+			stats.Groups = append(stats.Groups, group)
+			stats.Total += group.Count
+			stats.Overdue += group.Overdue
+		}
+		groups.Close()
+		if err := groups.Err(); err != nil {
+			return OutboxStats{}, err
+		}
+
+		// Step 2: Count the rows of the table per retry count.
+		buckets, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT retry_count, COUNT(*) FROM %s GROUP BY retry_count`, outboxTableName(index)))
+		if err != nil {
+			return OutboxStats{}, fmt.Errorf("histogram %s: %w", outboxTableName(index), err)
+		}
+		for buckets.Next() {
+			var bucket RetryBucket
+			if err := buckets.Scan(&bucket.RetryCount, &bucket.Count); err != nil {
+				buckets.Close()
+				return OutboxStats{}, fmt.Errorf("scan %s histogram: %w", outboxTableName(index), err)
+			}
+			histogram[bucket.RetryCount] += bucket.Count
+		}
+		buckets.Close()
+		if err := buckets.Err(); err != nil {
+			return OutboxStats{}, err
+		}
+	}
+
+	// Step 3: Order the groups and the histogram for stable output.
+	sort.Slice(stats.Groups, func(i, j int) bool {
+		a, b := stats.Groups[i], stats.Groups[j]
+		if a.TableIndex != b.TableIndex {
+			return a.TableIndex < b.TableIndex
+		}
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Status < b.Status
+	})
+	for retryCount, count := range histogram {
+		stats.RetryHistogram = append(stats.RetryHistogram, RetryBucket{RetryCount: retryCount, Count: count})
+	}
+	sort.Slice(stats.RetryHistogram, func(i, j int) bool {
+		return stats.RetryHistogram[i].RetryCount < stats.RetryHistogram[j].RetryCount
+	})
+
+	return stats, nil
+}
@@ -1,72 +1,171 @@
 // Package services provides service layer implementations for the Outbox Debugger application.
-// This file defines the function for subscribing to messages from Google Cloud Pub/Sub using the Watermill library.
+// This file defines the function for subscribing to messages from the configured broker using the Watermill library.
 package services
 
 import (
 	"context"
-	"outbox/debugger/enum"
+	"outbox/debugger/broker"
+	"outbox/debugger/config"
 	"outbox/debugger/helper"
 	"time"
 
-	"cloud.google.com/go/pubsub"
 	"github.com/ThreeDotsLabs/watermill"
-	"github.com/ThreeDotsLabs/watermill-googlecloud/pkg/googlecloud"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/rs/zerolog/log"
 )
 
+// ListenerOptions holds the topics and the observers of the deliveries handled by SubOutboxDebugger.
+// A nil observer is skipped.
+type ListenerOptions struct {
+	Topics      []string            // Topics to subscribe to; none means every outbox topic.
+	Tracker     *DeliveryTracker    // Records the delivered sequence numbers.
+	Latency     *LatencyRecorder    // Records the commit→publish and commit→receive latencies.
+	Faults      *FaultInjector      // Makes a fraction of the deliveries fail so they are redelivered.
+	Ordering    *OrderValidator     // Checks that every ordering key is delivered in strictly increasing sequence order.
+	Idempotency *helper.Idempotency // Skips the messages already processed and counts them.
+}
+
 // SubOutboxDebugger sets up a message subscriber for the Outbox Debugger.
 //
 // Parameters:
+//   - cfg: The runtime configuration providing the broker and subscription settings.
 //   - router: The message router responsible for handling incoming messages.
 //   - logger: The Watermill logger used for logging throughout the process.
+//   - opts: The topics to subscribe to and the observers recording the deliveries.
 //
 // Behavior:
-//   - Configures a Google Cloud Pub/Sub subscriber to listen to the specified topic.
-//   - Registers a no-publisher handler to process the incoming messages.
-//   - Processes messages by invoking a handler function.
+//   - Creates a subscriber of the configured broker to listen to opts.Topics, or to every outbox topic if none is given.
+//   - Classifies the handler errors with the rules of listener.errorRules, then with the built-in rules.
+//   - Registers one no-publisher handler per topic to process the incoming messages.
+//   - Forwards the poison messages of every handler to the dead-letter target of listener.deadLetter, if any.
+//   - Adds the throttle, retry and timeout middleware of listener.middleware to every handler, with the
+//     overrides of listener.handlers for its topic.
+//   - Warns about every event delivered on another topic than the one it was published to.
+//   - Processes messages by invoking a handler function, skipping the messages already processed when opts.Idempotency is set.
+//   - Verifies the checksum of every delivered DebugEvent and records its run, topic, ordering key and sequence number in the tracker.
+//   - Fails the deliveries chosen by the fault injector before recording them, so they are redelivered.
+//   - Abandons, without recording them, the deliveries whose context is done: the attempt timed out or the router stops.
+//   - Flags every delivered event whose transaction was deliberately rolled back.
+//   - Records the commit→publish and commit→receive latencies of every valid committed event.
+//   - Checks the sequence order of every valid committed event per topic and ordering key and logs every inversion.
 //
 // Error Handling:
-//   - Logs a fatal error and terminates the program if the subscriber creation fails.
-func SubOutboxDebugger(router *message.Router, logger watermill.LoggerAdapter) {
-	// Step 1: Configure the Pub/Sub subscriber
-	pubSubConfig := googlecloud.SubscriberConfig{
-		GenerateSubscriptionName:         func(topic string) string { return enum.SubscriberName },
-		ProjectID:                        enum.ProjectId,
-		DoNotCreateSubscriptionIfMissing: true,
-		SubscriptionConfig: pubsub.SubscriptionConfig{
-			EnableMessageOrdering: false,            // Disable message ordering
-			AckDeadline:           40 * time.Second, // Set acknowledgment deadline
-		},
+//   - Logs a fatal error and terminates the program if the subscriber or the dead-letter publisher creation fails,
+//     or if a rule of listener.errorRules is invalid.
+func SubOutboxDebugger(cfg *config.Config, router *message.Router, logger watermill.LoggerAdapter, opts ListenerOptions) {
+	// Step 1: Create the subscriber of the configured broker
+	subscriber, err := broker.NewSubscriber(cfg, logger)
+	if err != nil {
+		log.Fatal().Msgf("[OutboxDebugger] Could not create subscriber: %v", err) // Log and exit on error
 	}
 
-	// Step 2: Create the subscriber
-	subscriber, err := googlecloud.NewSubscriber(pubSubConfig, logger)
+	// Step 2: Create the middleware forwarding the poison messages to the dead-letter target
+	deadLetter, err := NewDeadLetterMiddleware(cfg, logger)
 	if err != nil {
-		log.Fatal().Msgf("[OutboxDebugger] Could not create subscriber: %v", err) // Log and exit on error
+		log.Fatal().Msgf("[OutboxDebugger] Could not create dead-letter middleware: %v", err) // Log and exit on error
+	}
+
+	// Step 3: Install the error rules classifying the handler errors
+	rules, err := listenerErrorRules(cfg)
+	if err != nil {
+		log.Fatal().Msgf("[OutboxDebugger] Invalid listener error rules: %v", err) // Log and exit on error
+	}
+	helper.SetErrorRules(rules...)
+
+	// Step 4: Add a no-publisher handler per topic to the router
+	topics := opts.Topics
+	if len(topics) == 0 {
+		topics = cfg.OutboxTopicNames()
 	}
+	for _, topic := range topics {
+		handler := router.AddNoPublisherHandler(
+			"OutboxDebugger/"+topic,           // Unique handler name
+			topic,                             // Topic to subscribe to
+			subscriber,                        // Subscriber instance
+			newDebugEventHandler(topic, opts), // Handler processing the messages of the topic
+		)
+		handler.AddMiddleware(handlerMiddleware(topic, cfg.HandlerMiddleware(topic), deadLetter, logger)...)
+	}
+}
+
+// newDebugEventHandler creates the handler of the DebugEvents delivered on a topic.
+//
+// Parameters:
+//   - topic: The topic the handler subscribes to.
+//   - opts: The observers recording the deliveries.
+//
+// Returns:
+//   - The handler function verifying, recording and acknowledging every delivered DebugEvent.
+func newDebugEventHandler(topic string, opts ListenerOptions) message.NoPublishHandlerFunc {
+	return func(msg *message.Message) error {
+		// Step 1: Process the message payload
+		return helper.WrapProcessMessagesIdempotentWithMeta[DebugEvent](
+			msg,
+			opts.Idempotency,
+			func(ctx context.Context, meta helper.MessageMeta, payload DebugEvent) error {
+				receivedAt := time.Now()
+
+				// Give up once the attempt timed out or the listener stops; the message is redelivered
+				if err := ctx.Err(); err != nil {
+					log.Warn().Msgf("Abandoning event %d of run %s: %v", payload.Sequence, payload.RunID, err)
+					return err
+				}
+
+				// Fail the delivery on purpose so the broker redelivers it
+				if opts.Faults.ShouldFail() {
+					log.Warn().Msgf("Failing delivery of event %d of run %s on purpose", payload.Sequence, payload.RunID)
+					return ErrInjectedFailure
+				}
 
-	// Step 3: Add a no-publisher handler to the router
-	router.AddNoPublisherHandler(
-		"OutboxDebugger", // Unique handler name
-		enum.TopicName,   // Topic to subscribe to
-		subscriber,       // Subscriber instance
-		func(msg *message.Message) error {
-			// Step 4: Process the message payload
-			return helper.WrapProcessMessages(
-				msg,
-				func(ctx context.Context, payload interface{}) error {
-					// Log the message payload for debugging or processing
-					log.Info().Msgf("Received payload: %v", payload)
+				// Log the message payload for debugging or processing
+				log.Info().Msgf("Received event %d of run %s (key %q, tx %d) as message %s (correlation id %s)",
+					payload.Sequence, payload.RunID, payload.OrderingKey, payload.TransactionID, meta.UUID, meta.CorrelationID)
 
-					// Acknowledge the message
-					msg.Ack()
+				// Verify the event and record the delivery for the end-of-run report
+				valid := payload.Valid()
+				eventTopic := payload.Topic
+				if eventTopic == "" {
+					eventTopic = topic
+				} else if eventTopic != topic {
+					log.Warn().Msgf("Event %d of run %s was delivered on topic %q instead of %q", payload.Sequence, payload.RunID, topic, eventTopic)
+				}
+				if !valid {
+					log.Error().Msgf("Checksum mismatch for event %d of run %s", payload.Sequence, payload.RunID)
+				}
+				if meta.OrderingKey != "" && meta.OrderingKey != payload.OrderingKey {
+					log.Warn().Msgf("Event %d of run %s was delivered with ordering key %q instead of %q", payload.Sequence, payload.RunID, meta.OrderingKey, payload.OrderingKey)
+				}
+				if valid && payload.Doomed {
+					log.Error().Msgf("Event %d of run %s was delivered although its transaction was rolled back", payload.Sequence, payload.RunID)
+				}
+				if opts.Tracker != nil {
+					switch {
+					case !valid:
+						opts.Tracker.RecordCorrupted()
+					case payload.Doomed:
+						opts.Tracker.RecordDoomed(payload.RunID, eventTopic, payload.OrderingKey, payload.Sequence)
+					default:
+						opts.Tracker.Record(payload.RunID, eventTopic, payload.OrderingKey, payload.Sequence)
+					}
+				}
+				if opts.Latency != nil && valid && !payload.Doomed {
+					opts.Latency.Record(topic, payload.OrderingKey, payload.CommittedAt, meta.PublishedAt, receivedAt)
+				}
+				if opts.Ordering != nil && valid && !payload.Doomed {
+					delivery := OrderedDelivery{Sequence: payload.Sequence, MessageID: meta.UUID, PublishedAt: meta.PublishedAt, ReceivedAt: receivedAt}
+					if inversion, inverted := opts.Ordering.Record(payload.RunID, eventTopic, payload.OrderingKey, delivery); inverted {
+						log.Warn().Msgf("Order inversion on topic %q, key %q of run %s: event %d (message %s) received after event %d (message %s)",
+							eventTopic, payload.OrderingKey, payload.RunID, payload.Sequence, meta.UUID, inversion.After.Sequence, inversion.After.MessageID)
+					}
+				}
 
-					// Return nil to indicate successful processing
-					return nil
-				},
-				"svc.sub.OutboxDebugger", // Tracing identifier for message processing.
-			)
-		},
-	)
+				// Acknowledge the message
+				msg.Ack()
+
+				// Return nil to indicate successful processing
+				return nil
+			},
+			"svc.sub.OutboxDebugger", // Tracing identifier for message processing.
+		)
+	}
 }